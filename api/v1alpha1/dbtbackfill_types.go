@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DbtBackfillSpec replays a project over the range from Start to End, split
+// into intervals that each become a DbtRun.
+//
+// Commands and the values of Vars are Go templates rendered per interval
+// with .Start and .End (formatted as dates when the interval is a whole
+// number of days, RFC 3339 otherwise), .StartTime and .EndTime (time.Time)
+// and .Index. The rendered Vars are passed to dbt as --vars.
+type DbtBackfillSpec struct {
+	ProjectRef corev1.LocalObjectReference `json:"projectRef"`
+	Start      metav1.Time                 `json:"start"`
+	End        metav1.Time                 `json:"end"`
+	// Interval is the length of each interval; the last one ends at End.
+	// Defaults to one day.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// MaxParallelism is how many intervals run at once. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	MaxParallelism int32 `json:"maxParallelism,omitempty"`
+	// Commands default to the project's commands. Without commands, the
+	// vars are merged over the project's invocation if it has one.
+	Commands []string `json:"commands,omitempty"`
+	// Vars default to start_date and end_date set to .Start and .End.
+	Vars map[string]string `json:"vars,omitempty"`
+	// BackoffLimit is how often a failed interval is retried automatically.
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit int32 `json:"backoffLimit,omitempty"`
+	// Suspend pauses the backfill: no new intervals start while it is set,
+	// but running ones finish.
+	Suspend bool `json:"suspend,omitempty"`
+	// RetryFailed starts the failed intervals again whenever it is
+	// increased.
+	RetryFailed int32 `json:"retryFailed,omitempty"`
+}
+
+type DbtBackfillStatus struct {
+	Phase               BackfillPhase      `json:"phase,omitempty"`
+	TotalIntervals      int32              `json:"totalIntervals,omitempty"`
+	SucceededIntervals  int32              `json:"succeededIntervals,omitempty"`
+	FailedIntervals     int32              `json:"failedIntervals,omitempty"`
+	RunningIntervals    int32              `json:"runningIntervals,omitempty"`
+	Intervals           []BackfillInterval `json:"intervals,omitempty"`
+	ObservedRetryFailed int32              `json:"observedRetryFailed,omitempty"`
+	StartTime           *metav1.Time       `json:"startTime,omitempty"`
+	CompletionTime      *metav1.Time       `json:"completionTime,omitempty"`
+	Conditions          []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration  int64              `json:"observedGeneration,omitempty"`
+}
+
+// BackfillInterval tracks one interval of a backfill and its latest run.
+type BackfillInterval struct {
+	Index    int32         `json:"index"`
+	Start    metav1.Time   `json:"start"`
+	End      metav1.Time   `json:"end"`
+	Phase    IntervalPhase `json:"phase,omitempty"`
+	Run      string        `json:"run,omitempty"`
+	Attempts int32         `json:"attempts,omitempty"`
+}
+
+type BackfillPhase string
+
+const (
+	BackfillPhasePending   BackfillPhase = "Pending"
+	BackfillPhaseRunning   BackfillPhase = "Running"
+	BackfillPhasePaused    BackfillPhase = "Paused"
+	BackfillPhaseSucceeded BackfillPhase = "Succeeded"
+	BackfillPhaseFailed    BackfillPhase = "Failed"
+	BackfillPhaseError     BackfillPhase = "Error"
+)
+
+type IntervalPhase string
+
+const (
+	IntervalPhasePending   IntervalPhase = "Pending"
+	IntervalPhaseRunning   IntervalPhase = "Running"
+	IntervalPhaseSucceeded IntervalPhase = "Succeeded"
+	IntervalPhaseFailed    IntervalPhase = "Failed"
+)
+
+const (
+	// BackfillConditionReady is false when the backfill cannot make progress
+	// because of its spec or its project; its reason says why.
+	BackfillConditionReady = "Ready"
+
+	BackfillReasonProjectNotFound = "ProjectNotFound"
+	BackfillReasonInvalidSpec     = "InvalidSpec"
+	BackfillReasonReconciled      = "Reconciled"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbtbf
+// +kubebuilder:printcolumn:name="Project",type="string",JSONPath=".spec.projectRef.name"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Succeeded",type="integer",JSONPath=".status.succeededIntervals"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedIntervals"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.totalIntervals"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+type DbtBackfill struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbtBackfillSpec   `json:"spec,omitempty"`
+	Status DbtBackfillStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+type DbtBackfillList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbtBackfill `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbtBackfill{}, &DbtBackfillList{})
+}
@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DbtCalendarSpec describes periods in which scheduled runs must not start,
+// shared by every DbtProject that references the calendar.
+type DbtCalendarSpec struct {
+	TimeZone        string           `json:"timeZone,omitempty"`
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+	// Dates are whole days (YYYY-MM-DD in the calendar's time zone) on which
+	// no scheduled run starts, e.g. public holidays.
+	Dates []string `json:"dates,omitempty"`
+}
+
+// BlackoutWindow is either a recurring window that opens at every fire time
+// of Schedule and stays open for Duration, or an absolute range from Start to
+// End.
+// +kubebuilder:validation:XValidation:rule="(has(self.schedule) && has(self.duration)) != (has(self.start) && has(self.end))",message="a blackout window needs either schedule and duration or start and end"
+// +kubebuilder:validation:XValidation:rule="!has(self.start) || !has(self.end) || self.end > self.start",message="end must be after start"
+type BlackoutWindow struct {
+	Name     string           `json:"name,omitempty"`
+	Schedule string           `json:"schedule,omitempty"`
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	Start    *metav1.Time     `json:"start,omitempty"`
+	End      *metav1.Time     `json:"end,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=dbtcal
+// +kubebuilder:printcolumn:name="Time Zone",type="string",JSONPath=".spec.timeZone"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+type DbtCalendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DbtCalendarSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+type DbtCalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbtCalendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbtCalendar{}, &DbtCalendarList{})
+}
@@ -2,36 +2,319 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// +kubebuilder:validation:XValidation:rule="!(has(self.commands) && has(self.invocation))",message="commands and invocation are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!(has(self.steps) && has(self.commands))",message="steps cannot be combined with commands"
+// +kubebuilder:validation:XValidation:rule="(has(self.git) && self.git.repository != ”) != has(self.source)",message="exactly one of git and source must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.schedule) || !has(self.schedules) || self.schedules.all(s, s.name != 'default')",message="schedule name \"default\" is reserved for spec.schedule"
 type DbtProjectSpec struct {
-	Git                        GitConfig                      `json:"git"`
-	Schedule                   string                         `json:"schedule,omitempty"`
-	Image                      string                         `json:"image,omitempty"`
-	ProfilesConfigMap          string                         `json:"profilesConfigMap,omitempty"`
-	ProfilesSecret             string                         `json:"profilesSecret,omitempty"`
-	Commands                   []string                       `json:"commands,omitempty"`
-	Env                        []corev1.EnvVar                `json:"env,omitempty"`
-	Resources                  corev1.ResourceRequirements    `json:"resources,omitempty"`
-	ServiceAccountName         string                         `json:"serviceAccountName,omitempty"`
-	SuccessfulJobsHistoryLimit *int32                         `json:"successfulJobsHistoryLimit,omitempty"`
-	FailedJobsHistoryLimit     *int32                         `json:"failedJobsHistoryLimit,omitempty"`
-	Suspend                    bool                           `json:"suspend,omitempty"`
-	VolumeClaimTemplates       []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
-	VolumeMounts               []corev1.VolumeMount           `json:"volumeMounts,omitempty"`
+	// Git is the git repository of the project, a shorthand for Source.Git.
+	// Its repository is empty for projects that set Source instead.
+	Git      GitConfig      `json:"git,omitempty"`
+	Source   *ProjectSource `json:"source,omitempty"`
+	Schedule string         `json:"schedule,omitempty"`
+	// TimeZone is the IANA time zone, e.g. "Europe/Berlin", in which the
+	// schedules and blackout windows are evaluated. It defaults to UTC. An
+	// unknown zone stops scheduling and sets the ScheduleInvalid condition.
+	TimeZone string `json:"timeZone,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	StartingDeadlineSeconds *int64            `json:"startingDeadlineSeconds,omitempty"`
+	CatchUp                 CatchUpPolicy     `json:"catchUp,omitempty"`
+	ConcurrencyPolicy       ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Schedules       []NamedSchedule  `json:"schedules,omitempty"`
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+	Calendars       []string         `json:"calendars,omitempty"`
+	Triggers        *ProjectTriggers `json:"triggers,omitempty"`
+	// Image runs dbt. It defaults to a plain Python image when an adapter
+	// is installed, and to dbt-postgres otherwise.
+	Image string `json:"image,omitempty"`
+	// Adapter is the dbt adapter installed for the project's runs, together
+	// with the dbt-core it depends on.
+	Adapter *DbtAdapter `json:"adapter,omitempty"`
+	// PythonPackages are further pip requirements installed with the
+	// adapter, e.g. "dbt-metricflow==0.4.0". Without an adapter they are
+	// added to the dbt of the image.
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z0-9][^\r\n]*$`
+	PythonPackages []string `json:"pythonPackages,omitempty"`
+	// PackageCache keeps the installed packages on a PVC per set of
+	// packages, so that runs only install them when they change. Without it
+	// every run installs them. All runs of the project share the PVC, so
+	// accessModes default to ReadWriteMany; ReadWriteOnce only suits runs
+	// that land on the same node.
+	PackageCache *corev1.PersistentVolumeClaimSpec `json:"packageCache,omitempty"`
+	// ProfilesConfigMap and ProfilesSecret are mounted together as the dbt
+	// profiles directory, so a profiles.yml from the ConfigMap can refer to
+	// files such as key files from the Secret. Their keys must not overlap.
+	ProfilesConfigMap string `json:"profilesConfigMap,omitempty"`
+	ProfilesSecret    string `json:"profilesSecret,omitempty"`
+	// Commands are the raw arguments to dbt. Runs that set neither commands
+	// nor an invocation use them.
+	Commands []string `json:"commands,omitempty"`
+	// Invocation is merged under the invocation of a run and under the
+	// invocations of steps, so its target or vars apply to all of them.
+	Invocation *DbtInvocation `json:"invocation,omitempty"`
+	// Steps are run by runs that set neither commands, an invocation nor
+	// steps of their own.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	Steps       []DbtStep    `json:"steps,omitempty"`
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	// TimeoutSeconds limits how long each attempt of a run may take. Runs
+	// that exceed it are stopped, giving dbt time to cancel its queries, and
+	// end TimedOut without being retried.
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+	// TerminationGracePeriodSeconds is how long dbt has to cancel its
+	// queries once a run's pod is stopped. Defaults to 120.
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64                      `json:"terminationGracePeriodSeconds,omitempty"`
+	Env                           []corev1.EnvVar             `json:"env,omitempty"`
+	Resources                     corev1.ResourceRequirements `json:"resources,omitempty"`
+	ServiceAccountName            string                      `json:"serviceAccountName,omitempty"`
+	SuccessfulJobsHistoryLimit    *int32                      `json:"successfulJobsHistoryLimit,omitempty"`
+	FailedJobsHistoryLimit        *int32                      `json:"failedJobsHistoryLimit,omitempty"`
+	Suspend                       bool                        `json:"suspend,omitempty"`
+	// VolumeClaimTemplates are PVCs the controller creates for the project's
+	// runs. Each becomes a volume named after the template, for use in
+	// VolumeMounts, so a template cannot take the name of a volume the
+	// controller adds itself.
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:XValidation:rule="self.all(t, has(t.metadata.name) && t.metadata.name != '')",message="volume claim templates need a name"
+	// +kubebuilder:validation:XValidation:rule="self.all(t, !has(t.metadata.name) || !(t.metadata.name in ['workspace', 'profiles', 'source', 'ssh-key', 'git-auth', 'oci-auth', 'dbt-state', 'dbt-packages']))",message="workspace, profiles, source, ssh-key, git-auth, oci-auth, dbt-state and dbt-packages are reserved volume names"
+	VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+	VolumeClaimScope     VolumeClaimScope               `json:"volumeClaimScope,omitempty"`
+	VolumeMounts         []corev1.VolumeMount           `json:"volumeMounts,omitempty"`
+	// PodTemplate is a partial pod template strategically merged into the
+	// pod template of the project's Jobs, e.g. to set a node selector, image
+	// pull secrets or security contexts, or to add sidecars. The image,
+	// command, arguments, working directory, environment, resources and volume
+	// mounts of the controller's containers, its volumes, the labels and
+	// annotations it sets and the restart policy cannot be overridden.
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplate *apiextensionsv1.JSON `json:"podTemplate,omitempty"`
+}
+
+// VolumeClaimScope decides which runs share the PVCs created from a
+// project's VolumeClaimTemplates.
+// +kubebuilder:validation:Enum=Project;Run
+type VolumeClaimScope string
+
+const (
+	// VolumeClaimScopeProject shares one PVC per template between all runs of
+	// the project and deletes it with the project. This is the default.
+	VolumeClaimScopeProject VolumeClaimScope = "Project"
+	// VolumeClaimScopeRun creates PVCs for every run and deletes them with
+	// the run.
+	VolumeClaimScopeRun VolumeClaimScope = "Run"
+)
+
+// NamedSchedule is an additional schedule of a project with its own dbt
+// commands, invocation or steps. Image, Env and Resources override the
+// project's defaults for the runs it creates; Env entries are merged by name.
+// +kubebuilder:validation:XValidation:rule="!(has(self.commands) && has(self.invocation))",message="commands and invocation are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!(has(self.steps) && (has(self.commands) || has(self.invocation)))",message="steps cannot be combined with commands or invocation"
+type NamedSchedule struct {
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"`
+	Commands []string `json:"commands,omitempty"`
+	// Invocation is merged over the project's invocation and replaces its
+	// commands or steps.
+	Invocation *DbtInvocation `json:"invocation,omitempty"`
+	// Steps replace the project's commands, invocation or steps.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	Steps     []DbtStep                    `json:"steps,omitempty"`
+	Image     string                       `json:"image,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	Suspend   bool                         `json:"suspend,omitempty"`
+}
+
+// CatchUpPolicy controls which missed schedule ticks are started when the
+// controller notices them late, e.g. after an operator restart or when a
+// project is resumed.
+// +kubebuilder:validation:Enum=None;LatestOnly;All
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone only starts a tick that is still on time. Without
+	// startingDeadlineSeconds a tick is on time for one minute.
+	CatchUpNone CatchUpPolicy = "None"
+	// CatchUpLatestOnly starts the most recent missed tick. This is the default.
+	CatchUpLatestOnly CatchUpPolicy = "LatestOnly"
+	// CatchUpAll starts a run for every missed tick, oldest first.
+	CatchUpAll CatchUpPolicy = "All"
+)
+
+// ConcurrencyPolicy decides what happens when a schedule tick, a trigger or
+// a webhook starts a run while another run the project started is active.
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent starts the new run alongside the active ones. This is
+	// the default.
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+	// ForbidConcurrent skips the tick, holds the trigger or refuses the
+	// webhook request while a run is active.
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent cancels the active runs and starts the new one once
+	// they have stopped.
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// ProjectTriggers starts runs of a project in response to other projects
+// instead of, or in addition to, its schedules.
+type ProjectTriggers struct {
+	// AfterProjects names projects in the same namespace whose successful
+	// runs trigger a run of this project.
+	AfterProjects       []string        `json:"afterProjects,omitempty"`
+	AfterProjectsPolicy UpstreamPolicy  `json:"afterProjectsPolicy,omitempty"`
+	Webhook             *WebhookTrigger `json:"webhook,omitempty"`
+}
+
+// WebhookTrigger lets authenticated HTTP requests to the manager's webhook
+// endpoint start runs of the project.
+type WebhookTrigger struct {
+	// SecretName names a Secret in the project's namespace. Its "token" key
+	// enables bearer token authentication and its "hmacKey" key enables
+	// HMAC-SHA256 signatures of the request body; a request passing either
+	// is accepted.
+	SecretName string `json:"secretName"`
+}
+
+// UpstreamPolicy decides which upstream successes trigger a run.
+// +kubebuilder:validation:Enum=Any;All
+type UpstreamPolicy string
+
+const (
+	// UpstreamAny triggers a run whenever any upstream project succeeds.
+	// This is the default.
+	UpstreamAny UpstreamPolicy = "Any"
+	// UpstreamAll triggers a run once every upstream project has succeeded
+	// since the last triggered run.
+	UpstreamAll UpstreamPolicy = "All"
+)
+
+// DbtAdapter is a dbt adapter package, dbt-<name>, at an exact version.
+type DbtAdapter struct {
+	// Name is the adapter without the dbt- prefix, e.g. duckdb or snowflake.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// +kubebuilder:validation:Pattern=`^[0-9][0-9A-Za-z.+!*]*$`
+	Version string `json:"version"`
+}
+
+// ProjectSource is where a project's dbt project comes from. Exactly one
+// kind of source is set; it is materialized into the workspace of each run's
+// pod before dbt starts.
+// +kubebuilder:validation:XValidation:rule="[has(self.git), has(self.oci), has(self.http), has(self.configMap), has(self.volumeClaim)].filter(s, s).size() == 1",message="exactly one kind of source must be set"
+type ProjectSource struct {
+	// Path is the directory of the dbt project within the source. For git
+	// sources git.path is used when it is unset.
+	Path        string             `json:"path,omitempty"`
+	Git         *GitConfig         `json:"git,omitempty"`
+	OCI         *OCISource         `json:"oci,omitempty"`
+	HTTP        *HTTPSource        `json:"http,omitempty"`
+	ConfigMap   *ConfigMapSource   `json:"configMap,omitempty"`
+	VolumeClaim *VolumeClaimSource `json:"volumeClaim,omitempty"`
+}
+
+// OCISource is an OCI artifact, e.g. pushed with oras push, pulled by digest
+// so that every run gets the same content.
+type OCISource struct {
+	// Repository is the artifact's repository, e.g. ghcr.io/acme/analytics.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9.-]*(:[0-9]+)?(/[a-z0-9]+([._-][a-z0-9]+)*)+$`
+	Repository string `json:"repository"`
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	Digest string `json:"digest"`
+	// PullSecret is a kubernetes.io/dockerconfigjson Secret holding the
+	// registry's credentials.
+	PullSecret string `json:"pullSecret,omitempty"`
+}
+
+// HTTPSource is a gzipped tarball downloaded over HTTP(S) and checked
+// against its checksum.
+type HTTPSource struct {
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+	// SHA256 is the hex encoded SHA-256 checksum of the tarball.
+	// +kubebuilder:validation:Pattern=`^[a-f0-9]{64}$`
+	SHA256 string `json:"sha256"`
+	// StripComponents drops that many leading directories from the paths in
+	// the tarball, e.g. 1 for GitHub archives.
+	// +kubebuilder:validation:Minimum=0
+	StripComponents int32 `json:"stripComponents,omitempty"`
+}
+
+// ConfigMapSource is a ConfigMap holding the files of a small dbt project.
+// Without Items every key becomes a file at the root of the project; as keys
+// cannot contain slashes, Items map keys to paths such as models/orders.sql,
+// and only the listed keys are used.
+type ConfigMapSource struct {
+	Name  string             `json:"name"`
+	Items []corev1.KeyToPath `json:"items,omitempty"`
+}
+
+// VolumeClaimSource is a PVC that already holds the dbt project, e.g. written
+// by a CI job. It is mounted read-only and copied into the workspace, so it
+// needs an access mode that lets runs on any node mount it.
+type VolumeClaimSource struct {
+	ClaimName string `json:"claimName"`
+	// SubPath is the directory of the volume that is copied.
+	SubPath string `json:"subPath,omitempty"`
 }
 
 type GitConfig struct {
-	Repository   string `json:"repository"`
-	Ref          string `json:"ref,omitempty"`
-	Path         string `json:"path,omitempty"`
-	SSHKeySecret string `json:"sshKeySecret,omitempty"`
-	AuthSecret   string `json:"authSecret,omitempty"`
+	Repository   string   `json:"repository"`
+	Ref          string   `json:"ref,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	SSHKeySecret string   `json:"sshKeySecret,omitempty"`
+	AuthSecret   string   `json:"authSecret,omitempty"`
+	Poll         *GitPoll `json:"poll,omitempty"`
+	// Depth limits the history that is fetched. Defaults to 1; 0 fetches the
+	// full history.
+	// +kubebuilder:validation:Minimum=0
+	Depth *int32 `json:"depth,omitempty"`
+	// Sparse only checks out Path (and files at the repository root).
+	Sparse bool `json:"sparse,omitempty"`
+	// Submodules also checks out the repository's submodules.
+	Submodules bool `json:"submodules,omitempty"`
+}
+
+// GitPoll makes the operator check the repository for new commits on Ref
+// and start a run whenever Ref moves.
+type GitPoll struct {
+	// Interval between checks. Defaults to five minutes; intervals below
+	// 30 seconds are raised to 30 seconds.
+	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
 type DbtProjectStatus struct {
-	LastScheduledTime  *metav1.Time             `json:"lastScheduledTime,omitempty"`
+	LastScheduledTime     *metav1.Time     `json:"lastScheduledTime,omitempty"`
+	NextScheduleTime      *metav1.Time     `json:"nextScheduleTime,omitempty"`
+	UpcomingScheduleTimes []metav1.Time    `json:"upcomingScheduleTimes,omitempty"`
+	Schedules             []ScheduleStatus `json:"schedules,omitempty"`
+	// LastTriggeredTime is the completion time of the latest upstream run
+	// that has been accounted for by a triggered run.
+	LastTriggeredTime *metav1.Time `json:"lastTriggeredTime,omitempty"`
+	// ResolvedCommit is the commit Ref pointed at when the repository was
+	// last polled.
+	ResolvedCommit string `json:"resolvedCommit,omitempty"`
+	// TriggeredCommit is the commit the latest GitChange run was started
+	// for, or the first commit resolved until the ref moves.
+	TriggeredCommit    string                   `json:"triggeredCommit,omitempty"`
+	LastPollTime       *metav1.Time             `json:"lastPollTime,omitempty"`
 	LastSuccessfulTime *metav1.Time             `json:"lastSuccessfulTime,omitempty"`
 	ActiveRuns         []corev1.ObjectReference `json:"activeRuns,omitempty"`
 	Phase              DbtProjectPhase          `json:"phase,omitempty"`
@@ -39,6 +322,56 @@ type DbtProjectStatus struct {
 	ObservedGeneration int64                    `json:"observedGeneration,omitempty"`
 }
 
+const (
+	// ProjectConditionTickSkipped is true when the most recent schedule tick
+	// was suppressed; its reason says why.
+	ProjectConditionTickSkipped = "TickSkipped"
+
+	ProjectReasonBlackout             = "Blackout"
+	ProjectReasonConcurrencyForbidden = "ConcurrencyForbidden"
+	ProjectReasonRunCreated           = "RunCreated"
+
+	// ProjectConditionUpstreamsPending is true while some upstream projects
+	// named in triggers.afterProjects have not succeeded since the last
+	// triggered run, while the triggered run waits for an active one, or
+	// while the project runs after itself.
+	ProjectConditionUpstreamsPending = "UpstreamsPending"
+
+	ProjectReasonWaitingForUpstreams = "WaitingForUpstreams"
+	ProjectReasonRunTriggered        = "RunTriggered"
+	// ProjectReasonUpstreamCycle reports that the project runs after itself
+	// through triggers.afterProjects, directly or through other projects.
+	// Its triggers are held until the cycle is broken.
+	ProjectReasonUpstreamCycle = "UpstreamCycle"
+
+	// ProjectConditionRefResolved reports whether the last poll of the git
+	// repository succeeded.
+	ProjectConditionRefResolved = "RefResolved"
+
+	ProjectReasonPollSucceeded = "PollSucceeded"
+	ProjectReasonPollFailed    = "PollFailed"
+
+	// ProjectConditionScheduleInvalid is true while the project's time zone,
+	// one of its schedules or its blackouts cannot be evaluated; its reason
+	// says which. No scheduled runs start until it is fixed, including while
+	// a referenced DbtCalendar does not exist, since runs could otherwise
+	// start in the blackouts it defines.
+	ProjectConditionScheduleInvalid = "ScheduleInvalid"
+
+	ProjectReasonInvalidSchedule       = "InvalidSchedule"
+	ProjectReasonInvalidBlackoutWindow = "InvalidBlackoutWindow"
+	ProjectReasonInvalidCalendar       = "InvalidCalendar"
+	ProjectReasonCalendarNotFound      = "CalendarNotFound"
+)
+
+// ScheduleStatus tracks one schedule of a project. The top-level schedule is
+// reported under the name "default".
+type ScheduleStatus struct {
+	Name              string       `json:"name"`
+	LastScheduledTime *metav1.Time `json:"lastScheduledTime,omitempty"`
+	NextScheduleTime  *metav1.Time `json:"nextScheduleTime,omitempty"`
+}
+
 type DbtProjectPhase string
 
 const (
@@ -54,6 +387,7 @@ const (
 // +kubebuilder:printcolumn:name="Suspend",type="boolean",JSONPath=".spec.suspend"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="Last Scheduled",type="date",JSONPath=".status.lastScheduledTime"
+// +kubebuilder:printcolumn:name="Next Scheduled",type="date",JSONPath=".status.nextScheduleTime"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 type DbtProject struct {
@@ -3,14 +3,144 @@ package v1alpha1
 import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// +kubebuilder:validation:XValidation:rule="!(has(self.commands) && has(self.invocation))",message="commands and invocation are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!(has(self.steps) && (has(self.commands) || has(self.invocation)))",message="steps cannot be combined with commands or invocation"
 type DbtRunSpec struct {
-	ProjectRef              corev1.LocalObjectReference `json:"projectRef"`
-	Type                    RunType                     `json:"type,omitempty"`
-	Commands                []string                    `json:"commands,omitempty"`
-	TTLSecondsAfterFinished *int32                      `json:"ttlSecondsAfterFinished,omitempty"`
+	ProjectRef corev1.LocalObjectReference `json:"projectRef"`
+	Type       RunType                     `json:"type,omitempty"`
+	// Commands are the raw arguments to dbt, used as they are.
+	Commands []string `json:"commands,omitempty"`
+	// Invocation is merged over the project's invocation.
+	Invocation *DbtInvocation `json:"invocation,omitempty"`
+	// Steps replace the project's commands, invocation or steps.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	Steps []DbtStep `json:"steps,omitempty"`
+	// RerunOf repeats a finished run of the same project.
+	RerunOf *RerunReference `json:"rerunOf,omitempty"`
+	// Image, Env, EnvFrom, Resources and GitRef replace or, for Env, are
+	// merged over those of the project and schedule.
+	Image     string                       `json:"image,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+	EnvFrom   []corev1.EnvFromSource       `json:"envFrom,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	GitRef    string                       `json:"gitRef,omitempty"`
+	// PodTemplate is merged over the project's pod template.
+	PodTemplate *RunPodTemplate `json:"podTemplate,omitempty"`
+	// Cancel stops the run. Its pod is stopped gracefully, so that dbt can
+	// cancel its queries; the run is Cancelling until the pod has stopped
+	// and then ends Cancelled.
+	Cancel bool `json:"cancel,omitempty"`
+	// TimeoutSeconds replaces the project's timeout.
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+	// RetryPolicy replaces the project's retry policy.
+	RetryPolicy             *RetryPolicy `json:"retryPolicy,omitempty"`
+	TTLSecondsAfterFinished *int32       `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// DbtInvocation describes a dbt command. Fields that are set override those
+// of the invocation it is merged over; Vars are merged by name.
+type DbtInvocation struct {
+	// Command is the dbt subcommand. Defaults to run.
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z-]*$`
+	Command  string   `json:"command,omitempty"`
+	Select   []string `json:"select,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Vars        map[string]apiextensionsv1.JSON `json:"vars,omitempty"`
+	Target      string                          `json:"target,omitempty"`
+	FullRefresh *bool                           `json:"fullRefresh,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	Threads  *int32 `json:"threads,omitempty"`
+	FailFast *bool  `json:"failFast,omitempty"`
+}
+
+// DbtStep is one dbt command of a multi-step run. Steps run one after the
+// other in the same workspace; a failing step ends the run unless
+// ContinueOnFailure is set. A step's invocation is not merged with others.
+// +kubebuilder:validation:XValidation:rule="!(has(self.commands) && has(self.invocation))",message="commands and invocation are mutually exclusive"
+type DbtStep struct {
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=50
+	Name              string         `json:"name"`
+	Commands          []string       `json:"commands,omitempty"`
+	Invocation        *DbtInvocation `json:"invocation,omitempty"`
+	ContinueOnFailure bool           `json:"continueOnFailure,omitempty"`
+}
+
+// RetryPolicy starts further attempts of a failed run. An attempt after dbt
+// reported failed nodes runs dbt retry, so only the failed and skipped nodes
+// run again; other failures repeat the failed command.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	MaxAttempts int32 `json:"maxAttempts"`
+	// Backoff is the delay before the first retry; it doubles for each
+	// further retry up to MaxDelay. Defaults to 30s.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// MaxDelay caps the delay between attempts. Defaults to 10m.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+	// On limits retries to these classes of failure. Defaults to all.
+	On []FailureClass `json:"on,omitempty"`
+	// StateVolume is the spec of the claim that keeps dbt's target directory
+	// between attempts and for reruns from failure. Runs keep it with or
+	// without a retry policy; it defaults to 1Gi ReadWriteOnce of the default
+	// storage class.
+	StateVolume *corev1.PersistentVolumeClaimSpec `json:"stateVolume,omitempty"`
+}
+
+// FailureClass classifies why an attempt of a run failed.
+// +kubebuilder:validation:Enum=NodeFailure;RuntimeError;PodFailure
+type FailureClass string
+
+const (
+	// FailureClassNode means dbt exited with 1: a model, test or other node
+	// failed.
+	FailureClassNode FailureClass = "NodeFailure"
+	// FailureClassRuntime means dbt exited with 2, e.g. because the project
+	// did not parse or the warehouse could not be reached.
+	FailureClassRuntime FailureClass = "RuntimeError"
+	// FailureClassPod covers everything else, such as a failed checkout, an
+	// OOM kill or an evicted pod.
+	FailureClassPod FailureClass = "PodFailure"
+)
+
+// RerunReference names the run a run repeats. The rerun takes over the dbt
+// commands, invocation or steps of the original unless it sets its own, and
+// checks out the commit the original checked out.
+type RerunReference struct {
+	Name string `json:"name"`
+	// FromFailure only runs the nodes that failed or were skipped in the
+	// original, using the run_results.json it left in its state volume.
+	// Every run that started dbt keeps that state.
+	FromFailure bool `json:"fromFailure,omitempty"`
+}
+
+// RunPodTemplate is the part of the pod template a run can override.
+type RunPodTemplate struct {
+	Metadata PodTemplateMetadata `json:"metadata,omitempty"`
+	Spec     RunPodSpec          `json:"spec,omitempty"`
+}
+
+type PodTemplateMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type RunPodSpec struct {
+	NodeSelector      map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations       []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity          *corev1.Affinity    `json:"affinity,omitempty"`
+	PriorityClassName string              `json:"priorityClassName,omitempty"`
 }
 
 type RunType string
@@ -19,6 +149,9 @@ const (
 	RunTypeScheduled RunType = "Scheduled"
 	RunTypeManual    RunType = "Manual"
 	RunTypeWebhook   RunType = "Webhook"
+	RunTypeUpstream  RunType = "Upstream"
+	RunTypeGitChange RunType = "GitChange"
+	RunTypeBackfill  RunType = "Backfill"
 )
 
 type DbtRunStatus struct {
@@ -26,12 +159,81 @@ type DbtRunStatus struct {
 	StartTime      *metav1.Time            `json:"startTime,omitempty"`
 	CompletionTime *metav1.Time            `json:"completionTime,omitempty"`
 	JobRef         *corev1.ObjectReference `json:"jobRef,omitempty"`
-	Conditions     []metav1.Condition      `json:"conditions,omitempty"`
-	JobStatus      *batchv1.JobStatus      `json:"jobStatus,omitempty"`
-	Logs           string                  `json:"logs,omitempty"`
-	Artifacts      map[string]string       `json:"artifacts,omitempty"`
+	// Commit is the commit the run checked out.
+	Commit     string             `json:"commit,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	JobStatus  *batchv1.JobStatus `json:"jobStatus,omitempty"`
+	Logs       string             `json:"logs,omitempty"`
+	Artifacts  map[string]string  `json:"artifacts,omitempty"`
+	Steps      []StepStatus       `json:"steps,omitempty"`
+	// Effective records the configuration the run's Jobs were created with.
+	Effective *EffectiveRunConfig `json:"effective,omitempty"`
+	// StateVolumeClaim is the PVC keeping dbt's target directory. Every run
+	// has one once it starts; a rerun from failure shares the original's.
+	StateVolumeClaim string `json:"stateVolumeClaim,omitempty"`
+	// RerunOf is the run this run repeats, and Reruns are the runs that
+	// repeat this one.
+	RerunOf string   `json:"rerunOf,omitempty"`
+	Reruns  []string `json:"reruns,omitempty"`
+	// ResumedAttempt is the failed attempt of the original run that a rerun
+	// from failure picks up from.
+	ResumedAttempt *RunAttempt `json:"resumedAttempt,omitempty"`
+	// Attempts lists the Jobs started for the run, the current one last.
+	Attempts      []RunAttempt `json:"attempts,omitempty"`
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+}
+
+// RunAttempt is one Job started for a run.
+type RunAttempt struct {
+	Attempt int32    `json:"attempt"`
+	Job     string   `json:"job"`
+	Phase   RunPhase `json:"phase,omitempty"`
+	// FailureClass, FailedContainer and ExitCode describe why a failed
+	// attempt failed.
+	FailureClass    FailureClass `json:"failureClass,omitempty"`
+	FailedContainer string       `json:"failedContainer,omitempty"`
+	ExitCode        *int32       `json:"exitCode,omitempty"`
+	StartTime       *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime  *metav1.Time `json:"completionTime,omitempty"`
 }
 
+// EffectiveRunConfig is the configuration of a run after merging the run
+// over its schedule and project.
+type EffectiveRunConfig struct {
+	Image     string                      `json:"image,omitempty"`
+	GitRef    string                      `json:"gitRef,omitempty"`
+	Env       []corev1.EnvVar             `json:"env,omitempty"`
+	EnvFrom   []corev1.EnvFromSource      `json:"envFrom,omitempty"`
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Args are the dbt arguments of a single-command run, built from
+	// Invocation if it has one; Steps are the steps of a multi-step run.
+	// Retries and reruns repeat them even if the project changed.
+	Args       []string       `json:"args,omitempty"`
+	Invocation *DbtInvocation `json:"invocation,omitempty"`
+	Steps      []DbtStep      `json:"steps,omitempty"`
+}
+
+// StepStatus is the progress of one step of a multi-step run.
+type StepStatus struct {
+	Name           string       `json:"name"`
+	Phase          StepPhase    `json:"phase,omitempty"`
+	ExitCode       *int32       `json:"exitCode,omitempty"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+type StepPhase string
+
+const (
+	StepPhasePending   StepPhase = "Pending"
+	StepPhaseRunning   StepPhase = "Running"
+	StepPhaseSucceeded StepPhase = "Succeeded"
+	StepPhaseFailed    StepPhase = "Failed"
+	// StepPhaseSkipped marks a step that never ran because an earlier step
+	// failed.
+	StepPhaseSkipped StepPhase = "Skipped"
+)
+
 type RunPhase string
 
 const (
@@ -40,6 +242,54 @@ const (
 	RunPhaseSucceeded RunPhase = "Succeeded"
 	RunPhaseFailed    RunPhase = "Failed"
 	RunPhaseError     RunPhase = "Error"
+	// RunPhaseTimedOut marks a run stopped because an attempt exceeded its
+	// timeout.
+	RunPhaseTimedOut RunPhase = "TimedOut"
+	// RunPhaseCancelling marks a cancelled run whose pod is still stopping,
+	// giving dbt the time to cancel its queries.
+	RunPhaseCancelling RunPhase = "Cancelling"
+	// RunPhaseCancelled marks a run stopped through spec.cancel.
+	RunPhaseCancelled RunPhase = "Cancelled"
+)
+
+const (
+	// RunConditionComplete is set once a run has reached a final phase; its
+	// reason explains how the run ended.
+	RunConditionComplete = "Complete"
+
+	// RunReasonSucceeded marks a run whose last attempt succeeded.
+	RunReasonSucceeded = "Succeeded"
+
+	// RunReasonFailed marks a run whose last attempt failed and was not
+	// retried.
+	RunReasonFailed = "Failed"
+
+	// RunReasonJobNotFound marks a run whose Job was deleted before it
+	// finished.
+	RunReasonJobNotFound = "JobNotFound"
+
+	// RunReasonReplaced marks a run that was cancelled because a newer run
+	// replaced it under the Replace concurrency policy.
+	RunReasonReplaced = "Replaced"
+
+	// RunReasonProfilesNotFound marks a run that could not start because the
+	// project's profiles ConfigMap or Secret does not exist.
+	RunReasonProfilesNotFound = "ProfilesNotFound"
+
+	// RunReasonKnownHostsNotFound marks a run that could not start because
+	// the SSH key Secret of its git repository has no known_hosts to check
+	// the server's host key against.
+	RunReasonKnownHostsNotFound = "KnownHostsNotFound"
+
+	// RunReasonTimedOut marks a run whose Job exceeded its active deadline.
+	RunReasonTimedOut = "TimedOut"
+
+	// RunReasonCancelled marks a run cancelled through spec.cancel.
+	RunReasonCancelled = "Cancelled"
+
+	// RunReasonInvalidRerun marks a rerun whose original run cannot be
+	// repeated.
+	RunReasonInvalidRerun = "InvalidRerun"
 )
 
 // +kubebuilder:object:root=true
@@ -2,19 +2,25 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
+	"time"
+	_ "time/tzdata" // DbtProject time zones; the base image has no zoneinfo
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
-	"github.com/robfig/cron/v3"
 	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
 	"github.com/scalecraft/dagctl-dbt/internal/controller"
 )
@@ -33,11 +39,15 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var webhookAddr string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	flag.StringVar(&webhookAddr, "webhook-bind-address", "0", "The address the webhook trigger endpoint binds to. "+
+		"Use 0 to disable it.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", true,
 		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
+			"Enabling this will ensure there is only one active controller manager. "+
+			"Only disable it when a single replica runs, e.g. locally.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -48,6 +58,11 @@ func main() {
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Pod{}: {Label: controller.RunPodSelector},
+			},
+		},
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
 		},
@@ -63,26 +78,57 @@ func main() {
 		os.Exit(1)
 	}
 
-	scheduler := cron.New(cron.WithSeconds())
-	scheduler.Start()
-
 	if err = (&controller.DbtProjectReconciler{
 		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
 		Scheme:    mgr.GetScheme(),
-		Scheduler: scheduler,
+		Recorder:  mgr.GetEventRecorderFor("dbtproject-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DbtProject")
 		os.Exit(1)
 	}
+	if err = (&controller.GitPoller{
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to set up git poller")
+		os.Exit(1)
+	}
 
 	if err = (&controller.DbtRunReconciler{
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DbtRun")
+		os.Exit(1)
+	}
+	if err = (&controller.DbtBackfillReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "DbtRun")
+		setupLog.Error(err, "unable to create controller", "controller", "DbtBackfill")
 		os.Exit(1)
 	}
 
+	if webhookAddr != "0" {
+		if err := mgr.Add(&manager.Server{
+			Name: "webhook-trigger",
+			Server: &http.Server{
+				Addr: webhookAddr,
+				Handler: &controller.WebhookHandler{
+					Client: mgr.GetClient(),
+					Reader: mgr.GetAPIReader(),
+					Scheme: mgr.GetScheme(),
+				},
+				ReadHeaderTimeout: 10 * time.Second,
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to set up webhook trigger endpoint")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// backfillLabel names the DbtBackfill that created a DbtRun.
+	backfillLabel = "orchestration.scalecraft.io/backfill"
+
+	// backfillIntervalAnnotation records the interval a backfill run covers.
+	backfillIntervalAnnotation = "orchestration.scalecraft.io/backfill-interval"
+
+	defaultBackfillInterval = 24 * time.Hour
+
+	// maxBackfillIntervals bounds the size of a backfill, whose intervals
+	// are all tracked in its status.
+	maxBackfillIntervals = 1000
+)
+
+// defaultBackfillVars are passed to dbt when a backfill sets no vars.
+var defaultBackfillVars = map[string]string{
+	"start_date": "{{ .Start }}",
+	"end_date":   "{{ .End }}",
+}
+
+// backfillIntervals splits the backfill's range into intervals.
+func backfillIntervals(spec *orchestrationv1alpha1.DbtBackfillSpec) ([]orchestrationv1alpha1.BackfillInterval, error) {
+	step := backfillStep(spec)
+	if step <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	if !spec.End.After(spec.Start.Time) {
+		return nil, fmt.Errorf("end %s must be after start %s", spec.End.Format(time.RFC3339), spec.Start.Format(time.RFC3339))
+	}
+	if count := spec.End.Sub(spec.Start.Time) / step; count >= maxBackfillIntervals {
+		return nil, fmt.Errorf("backfill would have more than %d intervals", maxBackfillIntervals)
+	}
+
+	var intervals []orchestrationv1alpha1.BackfillInterval
+	for start := spec.Start.Time; start.Before(spec.End.Time); start = start.Add(step) {
+		end := start.Add(step)
+		if end.After(spec.End.Time) {
+			end = spec.End.Time
+		}
+		intervals = append(intervals, orchestrationv1alpha1.BackfillInterval{
+			Index: int32(len(intervals)),
+			Start: metav1.Time{Time: start},
+			End:   metav1.Time{Time: end},
+			Phase: orchestrationv1alpha1.IntervalPhasePending,
+		})
+	}
+	return intervals, nil
+}
+
+func backfillStep(spec *orchestrationv1alpha1.DbtBackfillSpec) time.Duration {
+	if spec.Interval == nil {
+		return defaultBackfillInterval
+	}
+	return spec.Interval.Duration
+}
+
+// wholeDayIntervals reports whether every interval of a backfill starts and
+// ends at midnight UTC, so that its bounds can be passed as dates without
+// losing their time of day.
+func wholeDayIntervals(spec *orchestrationv1alpha1.DbtBackfillSpec) bool {
+	const day = 24 * time.Hour
+	return backfillStep(spec)%day == 0 &&
+		spec.Start.UTC().Truncate(day).Equal(spec.Start.Time) &&
+		spec.End.UTC().Truncate(day).Equal(spec.End.Time)
+}
+
+// backfillTemplateData is what the command and vars templates of a backfill
+// are rendered with.
+type backfillTemplateData struct {
+	Start, End         string
+	StartTime, EndTime time.Time
+	Index              int32
+}
+
+// backfillRunSpec returns the spec of the run of one interval of a backfill.
+// The rendered vars are passed with the backfill's commands. When the
+// backfill has no commands they are passed to every step of a project with
+// steps, or merged over the project's invocation when it uses one.
+func backfillRunSpec(backfill *orchestrationv1alpha1.DbtBackfill, project *orchestrationv1alpha1.DbtProject, interval orchestrationv1alpha1.BackfillInterval) (orchestrationv1alpha1.DbtRunSpec, error) {
+	spec := orchestrationv1alpha1.DbtRunSpec{
+		ProjectRef: backfill.Spec.ProjectRef,
+		Type:       orchestrationv1alpha1.RunTypeBackfill,
+	}
+
+	layout := time.RFC3339
+	if wholeDayIntervals(&backfill.Spec) {
+		layout = time.DateOnly
+	}
+	data := backfillTemplateData{
+		Start:     interval.Start.UTC().Format(layout),
+		End:       interval.End.UTC().Format(layout),
+		StartTime: interval.Start.UTC(),
+		EndTime:   interval.End.UTC(),
+		Index:     interval.Index,
+	}
+
+	templates := backfill.Spec.Vars
+	if len(templates) == 0 {
+		templates = defaultBackfillVars
+	}
+	vars := map[string]string{}
+	for name, value := range templates {
+		var err error
+		if vars[name], err = renderBackfillTemplate(value, data); err != nil {
+			return spec, err
+		}
+	}
+
+	if len(backfill.Spec.Commands) == 0 && (len(project.Spec.Steps) > 0 || project.Spec.Invocation != nil) {
+		converted, err := invocationVars(vars)
+		if err != nil {
+			return spec, err
+		}
+		if len(project.Spec.Steps) > 0 {
+			spec.Steps, err = stepsWithVars(project.Spec.Steps, converted)
+			return spec, err
+		}
+		spec.Invocation = &orchestrationv1alpha1.DbtInvocation{Vars: converted}
+		return spec, nil
+	}
+
+	commands := backfill.Spec.Commands
+	if len(commands) == 0 {
+		commands = project.Spec.Commands
+	}
+	if len(commands) == 0 {
+		commands = []string{defaultDbtCommand}
+	}
+	for _, command := range commands {
+		arg, err := renderBackfillTemplate(command, data)
+		if err != nil {
+			return spec, err
+		}
+		spec.Commands = append(spec.Commands, arg)
+	}
+
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return spec, err
+	}
+	spec.Commands, err = appendVars(spec.Commands, string(encoded))
+	return spec, err
+}
+
+func renderBackfillTemplate(text string, data backfillTemplateData) (string, error) {
+	tmpl, err := template.New("backfill").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return out.String(), nil
+}
+
+// backfillRunName derives a deterministic DbtRun name from the interval and
+// attempt so that repeated reconciles start each attempt once.
+func backfillRunName(backfill *orchestrationv1alpha1.DbtBackfill, interval orchestrationv1alpha1.BackfillInterval, attempt int32) string {
+	return boundedName(fmt.Sprintf("%s-%d-%d", backfill.Name, interval.Index, attempt), maxRunNameLength)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// blackoutsError reports blackouts that cannot be evaluated because of how
+// they are configured, as opposed to a failure to read them.
+type blackoutsError struct {
+	// reason is the reason of the project's ScheduleInvalid condition.
+	reason string
+	err    error
+}
+
+func (e *blackoutsError) Error() string { return e.err.Error() }
+func (e *blackoutsError) Unwrap() error { return e.err }
+
+// blackoutRule suppresses scheduled runs during the times it covers.
+type blackoutRule struct {
+	// source describes the rule in events and conditions.
+	source string
+	covers func(t time.Time) bool
+}
+
+type blackoutRules []blackoutRule
+
+// blockedBy returns the source of the first rule that covers t, or an empty
+// string when runs may start at t.
+func (rules blackoutRules) blockedBy(t time.Time) string {
+	for _, rule := range rules {
+		if rule.covers(t) {
+			return rule.source
+		}
+	}
+	return ""
+}
+
+// newBlackoutWindowRules resolves blackout windows, evaluating recurring
+// windows in the given time zone. owner names where the windows are defined.
+func newBlackoutWindowRules(owner string, windows []orchestrationv1alpha1.BlackoutWindow, timeZone string) (blackoutRules, error) {
+	rules := make(blackoutRules, 0, len(windows))
+	for i, window := range windows {
+		source := fmt.Sprintf("%s blackout window %d", owner, i)
+		if window.Name != "" {
+			source = fmt.Sprintf("%s blackout window %q", owner, window.Name)
+		}
+
+		switch {
+		case window.Schedule != "" && window.Duration != nil:
+			sched, err := parseCron(window.Schedule, timeZone)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			duration := window.Duration.Duration
+			rules = append(rules, blackoutRule{
+				source: source,
+				covers: func(t time.Time) bool {
+					// The window covers t if it opened within the last duration.
+					opened := sched.Next(t.Add(-duration))
+					return !opened.IsZero() && !opened.After(t)
+				},
+			})
+		case window.Start != nil && window.End != nil:
+			start, end := window.Start.Time, window.End.Time
+			if !end.After(start) {
+				return nil, fmt.Errorf("%s: end must be after start", source)
+			}
+			rules = append(rules, blackoutRule{
+				source: source,
+				covers: func(t time.Time) bool {
+					return !t.Before(start) && t.Before(end)
+				},
+			})
+		default:
+			return nil, fmt.Errorf("%s: needs either schedule and duration or start and end", source)
+		}
+	}
+	return rules, nil
+}
+
+// newCalendarRules resolves the blackout windows and dates of a DbtCalendar.
+func newCalendarRules(calendar *orchestrationv1alpha1.DbtCalendar) (blackoutRules, error) {
+	owner := fmt.Sprintf("calendar %q", calendar.Name)
+	rules, err := newBlackoutWindowRules(owner, calendar.Spec.BlackoutWindows, calendar.Spec.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(calendar.Spec.Dates) == 0 {
+		return rules, nil
+	}
+
+	loc := time.UTC
+	if calendar.Spec.TimeZone != "" {
+		if loc, err = time.LoadLocation(calendar.Spec.TimeZone); err != nil {
+			return nil, fmt.Errorf("%s: unknown time zone %q: %w", owner, calendar.Spec.TimeZone, err)
+		}
+	}
+
+	dates := make(map[string]bool, len(calendar.Spec.Dates))
+	for _, date := range calendar.Spec.Dates {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("%s: invalid date %q, expected YYYY-MM-DD", owner, date)
+		}
+		dates[date] = true
+	}
+
+	return append(rules, blackoutRule{
+		source: owner,
+		covers: func(t time.Time) bool {
+			return dates[t.In(loc).Format(time.DateOnly)]
+		},
+	}), nil
+}
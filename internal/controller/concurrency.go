@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// replacesAnnotation lists the runs a webhook run replaced under the
+	// Replace concurrency policy. The run does not start before they have
+	// stopped.
+	replacesAnnotation = "orchestration.scalecraft.io/replaces"
+
+	// replaceRequeueInterval is how often a run waiting for the runs it
+	// replaces checks whether they have stopped.
+	replaceRequeueInterval = 5 * time.Second
+)
+
+// startedByProject reports whether the project started run from a schedule,
+// a trigger or its webhook. Only those runs are subject to its concurrency
+// policy; manual runs and backfills neither block nor get replaced by them.
+func startedByProject(run orchestrationv1alpha1.DbtRun) bool {
+	switch run.Spec.Type {
+	case orchestrationv1alpha1.RunTypeScheduled,
+		orchestrationv1alpha1.RunTypeUpstream,
+		orchestrationv1alpha1.RunTypeGitChange,
+		orchestrationv1alpha1.RunTypeWebhook:
+		return true
+	}
+	return false
+}
+
+// concurrentRuns returns the active runs a new run of the project started
+// from a schedule, a trigger or its webhook has to respect.
+func concurrentRuns(activeRuns []orchestrationv1alpha1.DbtRun) []orchestrationv1alpha1.DbtRun {
+	var concurrent []orchestrationv1alpha1.DbtRun
+	for _, run := range activeRuns {
+		if startedByProject(run) {
+			concurrent = append(concurrent, run)
+		}
+	}
+	return concurrent
+}
+
+// stoppingRun reports whether run was cancelled, by the Replace policy or
+// otherwise, but has not stopped yet.
+func stoppingRun(run orchestrationv1alpha1.DbtRun) bool {
+	return run.Spec.Cancel && !isRunFinished(run.Status.Phase)
+}
+
+// listActiveRuns returns the project's runs, scheduled or not, that have not
+// reached a final phase yet, oldest first.
+func listActiveRuns(ctx context.Context, reader client.Reader, project *orchestrationv1alpha1.DbtProject) ([]orchestrationv1alpha1.DbtRun, error) {
+	var runs orchestrationv1alpha1.DbtRunList
+	if err := reader.List(ctx, &runs, client.InNamespace(project.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var active []orchestrationv1alpha1.DbtRun
+	for _, run := range runs.Items {
+		if run.Spec.ProjectRef.Name == project.Name && !isRunFinished(run.Status.Phase) {
+			active = append(active, run)
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].CreationTimestamp.Before(&active[j].CreationTimestamp) ||
+			(active[i].CreationTimestamp.Equal(&active[j].CreationTimestamp) && active[i].Name < active[j].Name)
+	})
+
+	return active, nil
+}
+
+// replaceRun cancels an active run on behalf of the Replace concurrency
+// policy through spec.cancel; the DbtRun controller stops its Job and ends
+// it Cancelled with the Replaced reason.
+func replaceRun(ctx context.Context, c client.Client, run *orchestrationv1alpha1.DbtRun) error {
+	if run.Spec.Cancel {
+		return nil
+	}
+	patch := client.MergeFrom(run.DeepCopy())
+	run.Spec.Cancel = true
+	if run.Annotations == nil {
+		run.Annotations = map[string]string{}
+	}
+	run.Annotations[cancelReasonAnnotation] = orchestrationv1alpha1.RunReasonReplaced
+	return client.IgnoreNotFound(c.Patch(ctx, run, patch))
+}
+
+// replacedRunsStopping reports whether any of the runs named in the run's
+// replacesAnnotation has not stopped yet. Runs that no longer exist have.
+func replacedRunsStopping(ctx context.Context, reader client.Reader, run *orchestrationv1alpha1.DbtRun) (bool, error) {
+	replaced := run.Annotations[replacesAnnotation]
+	if replaced == "" {
+		return false, nil
+	}
+	for _, name := range strings.Split(replaced, ",") {
+		var other orchestrationv1alpha1.DbtRun
+		if err := reader.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: name}, &other); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if !isRunFinished(other.Status.Phase) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// runNames returns the names of runs.
+func runNames(runs []orchestrationv1alpha1.DbtRun) []string {
+	names := make([]string, 0, len(runs))
+	for _, run := range runs {
+		names = append(names, run.Name)
+	}
+	return names
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+type DbtBackfillReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtbackfills,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtbackfills/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtbackfills/finalizers,verbs=update
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtprojects,verbs=get;list;watch
+
+func (r *DbtBackfillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	var backfill orchestrationv1alpha1.DbtBackfill
+	if err := r.Get(ctx, req.NamespacedName, &backfill); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if backfill.Status.Phase == orchestrationv1alpha1.BackfillPhaseSucceeded {
+		return ctrl.Result{}, nil
+	}
+
+	original := backfill.Status.DeepCopy()
+	status := &backfill.Status
+	status.ObservedGeneration = backfill.Generation
+
+	var project orchestrationv1alpha1.DbtProject
+	projectKey := client.ObjectKey{Namespace: backfill.Namespace, Name: backfill.Spec.ProjectRef.Name}
+	if err := r.Get(ctx, projectKey, &project); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// Projects are not watched, so check again later.
+		return ctrl.Result{RequeueAfter: time.Minute}, r.setBackfillError(ctx, &backfill, original,
+			orchestrationv1alpha1.BackfillReasonProjectNotFound, fmt.Sprintf("DbtProject %q not found", projectKey.Name))
+	}
+
+	if len(status.Intervals) == 0 {
+		intervals, err := backfillIntervals(&backfill.Spec)
+		if err != nil {
+			return ctrl.Result{}, r.setBackfillError(ctx, &backfill, original,
+				orchestrationv1alpha1.BackfillReasonInvalidSpec, err.Error())
+		}
+		now := metav1.Now()
+		status.Intervals = intervals
+		status.TotalIntervals = int32(len(intervals))
+		status.StartTime = &now
+	}
+
+	var runs orchestrationv1alpha1.DbtRunList
+	if err := r.List(ctx, &runs, client.InNamespace(backfill.Namespace), client.MatchingLabels{backfillLabel: backfill.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+	runsByName := map[string]*orchestrationv1alpha1.DbtRun{}
+	for i := range runs.Items {
+		runsByName[runs.Items[i].Name] = &runs.Items[i]
+	}
+
+	for i := range status.Intervals {
+		interval := &status.Intervals[i]
+		if interval.Phase != orchestrationv1alpha1.IntervalPhaseRunning {
+			continue
+		}
+		run, ok := runsByName[interval.Run]
+		if !ok {
+			// The run was deleted before it finished; start another attempt.
+			interval.Phase = orchestrationv1alpha1.IntervalPhasePending
+			continue
+		}
+		switch run.Status.Phase {
+		case orchestrationv1alpha1.RunPhaseSucceeded:
+			interval.Phase = orchestrationv1alpha1.IntervalPhaseSucceeded
+		case orchestrationv1alpha1.RunPhaseCancelled:
+			// A cancelled run is not retried.
+			interval.Phase = orchestrationv1alpha1.IntervalPhaseFailed
+		case orchestrationv1alpha1.RunPhaseFailed, orchestrationv1alpha1.RunPhaseError, orchestrationv1alpha1.RunPhaseTimedOut:
+			if interval.Attempts <= backfill.Spec.BackoffLimit {
+				interval.Phase = orchestrationv1alpha1.IntervalPhasePending
+			} else {
+				interval.Phase = orchestrationv1alpha1.IntervalPhaseFailed
+			}
+		}
+	}
+
+	if backfill.Spec.RetryFailed != status.ObservedRetryFailed {
+		for i := range status.Intervals {
+			if status.Intervals[i].Phase == orchestrationv1alpha1.IntervalPhaseFailed {
+				status.Intervals[i].Phase = orchestrationv1alpha1.IntervalPhasePending
+			}
+		}
+		status.ObservedRetryFailed = backfill.Spec.RetryFailed
+		status.CompletionTime = nil
+	}
+
+	if !backfill.Spec.Suspend {
+		maxParallelism := max(backfill.Spec.MaxParallelism, 1)
+		running := countIntervals(status.Intervals, orchestrationv1alpha1.IntervalPhaseRunning)
+		for i := range status.Intervals {
+			if running >= maxParallelism {
+				break
+			}
+			interval := &status.Intervals[i]
+			if interval.Phase != orchestrationv1alpha1.IntervalPhasePending {
+				continue
+			}
+
+			spec, err := backfillRunSpec(&backfill, &project, *interval)
+			if err != nil {
+				return ctrl.Result{}, r.setBackfillError(ctx, &backfill, original,
+					orchestrationv1alpha1.BackfillReasonInvalidSpec, err.Error())
+			}
+			run, err := r.createBackfillRun(ctx, &backfill, *interval, interval.Attempts+1, spec)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info("Started backfill interval", "run", run.Name, "start", interval.Start, "end", interval.End)
+
+			interval.Phase = orchestrationv1alpha1.IntervalPhaseRunning
+			interval.Run = run.Name
+			interval.Attempts++
+			running++
+		}
+	}
+
+	status.SucceededIntervals = countIntervals(status.Intervals, orchestrationv1alpha1.IntervalPhaseSucceeded)
+	status.FailedIntervals = countIntervals(status.Intervals, orchestrationv1alpha1.IntervalPhaseFailed)
+	status.RunningIntervals = countIntervals(status.Intervals, orchestrationv1alpha1.IntervalPhaseRunning)
+	pending := countIntervals(status.Intervals, orchestrationv1alpha1.IntervalPhasePending)
+
+	switch {
+	case status.SucceededIntervals == status.TotalIntervals:
+		status.Phase = orchestrationv1alpha1.BackfillPhaseSucceeded
+	case status.RunningIntervals == 0 && pending == 0:
+		status.Phase = orchestrationv1alpha1.BackfillPhaseFailed
+	case backfill.Spec.Suspend:
+		status.Phase = orchestrationv1alpha1.BackfillPhasePaused
+	default:
+		status.Phase = orchestrationv1alpha1.BackfillPhaseRunning
+	}
+	if (status.Phase == orchestrationv1alpha1.BackfillPhaseSucceeded || status.Phase == orchestrationv1alpha1.BackfillPhaseFailed) &&
+		status.CompletionTime == nil {
+		now := metav1.Now()
+		status.CompletionTime = &now
+	}
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               orchestrationv1alpha1.BackfillConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             orchestrationv1alpha1.BackfillReasonReconciled,
+		Message:            fmt.Sprintf("%d of %d intervals succeeded", status.SucceededIntervals, status.TotalIntervals),
+		ObservedGeneration: backfill.Generation,
+	})
+
+	if !equality.Semantic.DeepEqual(original, status) {
+		if err := r.Status().Update(ctx, &backfill); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setBackfillError records why the backfill cannot make progress.
+func (r *DbtBackfillReconciler) setBackfillError(ctx context.Context, backfill *orchestrationv1alpha1.DbtBackfill, original *orchestrationv1alpha1.DbtBackfillStatus, reason, message string) error {
+	backfill.Status.Phase = orchestrationv1alpha1.BackfillPhaseError
+	meta.SetStatusCondition(&backfill.Status.Conditions, metav1.Condition{
+		Type:               orchestrationv1alpha1.BackfillConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: backfill.Generation,
+	})
+	if equality.Semantic.DeepEqual(original, &backfill.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, backfill)
+}
+
+func (r *DbtBackfillReconciler) createBackfillRun(ctx context.Context, backfill *orchestrationv1alpha1.DbtBackfill, interval orchestrationv1alpha1.BackfillInterval, attempt int32, spec orchestrationv1alpha1.DbtRunSpec) (*orchestrationv1alpha1.DbtRun, error) {
+	run := &orchestrationv1alpha1.DbtRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backfillRunName(backfill, interval, attempt),
+			Namespace: backfill.Namespace,
+			Labels: map[string]string{
+				backfillLabel: backfill.Name,
+			},
+			Annotations: map[string]string{
+				backfillIntervalAnnotation: fmt.Sprintf("%s/%s", formatTick(interval.Start.Time), formatTick(interval.End.Time)),
+			},
+		},
+		Spec: spec,
+	}
+
+	if err := controllerutil.SetControllerReference(backfill, run, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+
+	if err := r.Create(ctx, run); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create backfill run: %w", err)
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+			return nil, err
+		}
+	}
+
+	return run, nil
+}
+
+func countIntervals(intervals []orchestrationv1alpha1.BackfillInterval, phase orchestrationv1alpha1.IntervalPhase) int32 {
+	var count int32
+	for _, interval := range intervals {
+		if interval.Phase == phase {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *DbtBackfillReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&orchestrationv1alpha1.DbtBackfill{}).
+		Owns(&orchestrationv1alpha1.DbtRun{}).
+		Complete(r)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025 ScaleCraft.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+var _ = Describe("DbtBackfill Controller", func() {
+	const (
+		projectName  = "backfill-project"
+		backfillName = "backfill-test"
+	)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: backfillName, Namespace: "default"}
+	var reconciler *DbtBackfillReconciler
+
+	BeforeEach(func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: projectName, Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:      orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Commands: []string{"build"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		backfill := &orchestrationv1alpha1.DbtBackfill{
+			ObjectMeta: metav1.ObjectMeta{Name: backfillName, Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtBackfillSpec{
+				ProjectRef:     corev1.LocalObjectReference{Name: projectName},
+				Start:          metav1.NewTime(start),
+				End:            metav1.NewTime(start.AddDate(0, 0, 3)),
+				MaxParallelism: 2,
+			},
+		}
+		Expect(k8sClient.Create(ctx, backfill)).To(Succeed())
+
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, backfill)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &orchestrationv1alpha1.DbtRun{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		reconciler = &DbtBackfillReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+	})
+
+	reconcileBackfill := func() *orchestrationv1alpha1.DbtBackfill {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		var backfill orchestrationv1alpha1.DbtBackfill
+		Expect(k8sClient.Get(ctx, key, &backfill)).To(Succeed())
+		return &backfill
+	}
+
+	finishRun := func(name string, phase orchestrationv1alpha1.RunPhase) {
+		var run orchestrationv1alpha1.DbtRun
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &run)).To(Succeed())
+		run.Status.Phase = phase
+		Expect(k8sClient.Status().Update(ctx, &run)).To(Succeed())
+	}
+
+	It("passes the vars of an interval to every step of a project with steps", func() {
+		var backfill orchestrationv1alpha1.DbtBackfill
+		Expect(k8sClient.Get(ctx, key, &backfill)).To(Succeed())
+		project := &orchestrationv1alpha1.DbtProject{
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Steps: []orchestrationv1alpha1.DbtStep{
+					{Name: "deps", Commands: []string{"deps"}},
+					{Name: "seed", Commands: []string{"seed"}},
+					{Name: "build", Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build"}},
+				},
+			},
+		}
+		intervals, err := backfillIntervals(&backfill.Spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		spec, err := backfillRunSpec(&backfill, project, intervals[0])
+		Expect(err).NotTo(HaveOccurred())
+		vars := `{"end_date":"2025-01-02","start_date":"2025-01-01"}`
+		Expect(spec.Commands).To(BeEmpty())
+		Expect(spec.Steps).To(HaveLen(3))
+		Expect(spec.Steps[1].Commands).To(Equal([]string{"seed", "--vars", vars}))
+		args, err := stepArgs(spec.Steps[2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"build", "--vars", vars}))
+		Expect(project.Spec.Steps[1].Commands).To(Equal([]string{"seed"}))
+	})
+
+	It("renders the time of day of intervals that do not start at midnight", func() {
+		var backfill orchestrationv1alpha1.DbtBackfill
+		Expect(k8sClient.Get(ctx, key, &backfill)).To(Succeed())
+		project := &orchestrationv1alpha1.DbtProject{}
+		render := func() []string {
+			intervals, err := backfillIntervals(&backfill.Spec)
+			Expect(err).NotTo(HaveOccurred())
+			var vars []string
+			for _, interval := range intervals {
+				spec, err := backfillRunSpec(&backfill, project, interval)
+				Expect(err).NotTo(HaveOccurred())
+				vars = append(vars, spec.Commands[len(spec.Commands)-1])
+			}
+			return vars
+		}
+
+		backfill.Spec.Start = metav1.NewTime(backfill.Spec.Start.Add(6 * time.Hour))
+		Expect(render()).To(Equal([]string{
+			`{"end_date":"2025-01-02T06:00:00Z","start_date":"2025-01-01T06:00:00Z"}`,
+			`{"end_date":"2025-01-03T06:00:00Z","start_date":"2025-01-02T06:00:00Z"}`,
+			`{"end_date":"2025-01-04T00:00:00Z","start_date":"2025-01-03T06:00:00Z"}`,
+		}))
+
+		backfill.Spec.Start = metav1.NewTime(backfill.Spec.Start.Add(-6 * time.Hour))
+		backfill.Spec.End = metav1.NewTime(backfill.Spec.End.Add(-time.Hour))
+		Expect(render()[2]).To(Equal(`{"end_date":"2025-01-03T23:00:00Z","start_date":"2025-01-03T00:00:00Z"}`))
+	})
+
+	It("runs intervals up to maxParallelism and retries failed ones on request", func() {
+		backfill := reconcileBackfill()
+		Expect(backfill.Status.TotalIntervals).To(Equal(int32(3)))
+		Expect(backfill.Status.RunningIntervals).To(Equal(int32(2)))
+		Expect(backfill.Status.Phase).To(Equal(orchestrationv1alpha1.BackfillPhaseRunning))
+
+		var run orchestrationv1alpha1.DbtRun
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: backfillName + "-0-1", Namespace: "default"}, &run)).To(Succeed())
+		Expect(run.Spec.Type).To(Equal(orchestrationv1alpha1.RunTypeBackfill))
+		Expect(run.Spec.Commands).To(Equal([]string{"build", "--vars", `{"end_date":"2025-01-02","start_date":"2025-01-01"}`}))
+		Expect(metav1.IsControlledBy(&run, backfill)).To(BeTrue())
+
+		finishRun(backfillName+"-0-1", orchestrationv1alpha1.RunPhaseSucceeded)
+		finishRun(backfillName+"-1-1", orchestrationv1alpha1.RunPhaseFailed)
+		backfill = reconcileBackfill()
+		Expect(backfill.Status.SucceededIntervals).To(Equal(int32(1)))
+		Expect(backfill.Status.FailedIntervals).To(Equal(int32(1)))
+		Expect(backfill.Status.Intervals[2].Run).To(Equal(backfillName + "-2-1"))
+
+		finishRun(backfillName+"-2-1", orchestrationv1alpha1.RunPhaseSucceeded)
+		backfill = reconcileBackfill()
+		Expect(backfill.Status.Phase).To(Equal(orchestrationv1alpha1.BackfillPhaseFailed))
+		Expect(backfill.Status.CompletionTime).NotTo(BeNil())
+
+		backfill.Spec.RetryFailed = 1
+		Expect(k8sClient.Update(ctx, backfill)).To(Succeed())
+		backfill = reconcileBackfill()
+		Expect(backfill.Status.Phase).To(Equal(orchestrationv1alpha1.BackfillPhaseRunning))
+		Expect(backfill.Status.Intervals[1].Run).To(Equal(backfillName + "-1-2"))
+		Expect(backfill.Status.Intervals[1].Attempts).To(Equal(int32(2)))
+
+		finishRun(backfillName+"-1-2", orchestrationv1alpha1.RunPhaseSucceeded)
+		backfill = reconcileBackfill()
+		Expect(backfill.Status.Phase).To(Equal(orchestrationv1alpha1.BackfillPhaseSucceeded))
+	})
+})
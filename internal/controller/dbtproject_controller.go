@@ -2,14 +2,21 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,23 +26,27 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	"github.com/robfig/cron/v3"
 	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
 )
 
 type DbtProjectReconciler struct {
 	client.Client
+	// APIReader reads Secrets uncached; it defaults to Client.
+	APIReader client.Reader
 	Scheme    *runtime.Scheme
-	Scheduler *cron.Cron
+	Recorder  record.EventRecorder
+	Clock     clock.PassiveClock
 }
 
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtprojects,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtprojects/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtprojects/finalizers,verbs=update
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtcalendars,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
-// +kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *DbtProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
@@ -56,106 +67,566 @@ func (r *DbtProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	activeRuns, err := listActiveRuns(ctx, r.Client, &dbtProject)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if refs := runReferences(activeRuns); !equality.Semantic.DeepEqual(refs, dbtProject.Status.ActiveRuns) {
+		dbtProject.Status.ActiveRuns = refs
+		if err := r.Status().Update(ctx, &dbtProject); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	if dbtProject.Spec.Suspend {
-		if dbtProject.Status.Phase != orchestrationv1alpha1.DbtProjectPhaseSuspended {
+		cleared := setUpcomingSchedules(&dbtProject.Status, nil)
+		for i := range dbtProject.Status.Schedules {
+			if dbtProject.Status.Schedules[i].NextScheduleTime != nil {
+				dbtProject.Status.Schedules[i].NextScheduleTime = nil
+				cleared = true
+			}
+		}
+		if dbtProject.Status.Phase != orchestrationv1alpha1.DbtProjectPhaseSuspended || cleared {
 			dbtProject.Status.Phase = orchestrationv1alpha1.DbtProjectPhaseSuspended
 			if err := r.Status().Update(ctx, &dbtProject); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
-		r.removeScheduledJob(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
-	if dbtProject.Spec.Schedule != "" {
-		if err := r.scheduleProject(ctx, &dbtProject); err != nil {
-			log.Error(err, "Failed to schedule project")
-			dbtProject.Status.Phase = orchestrationv1alpha1.DbtProjectPhaseError
-			r.Status().Update(ctx, &dbtProject)
-			return ctrl.Result{RequeueAfter: 1 * time.Minute}, err
-		}
+	if err := validateSchedules(&dbtProject); err != nil {
+		return ctrl.Result{}, r.invalidateSchedules(ctx, &dbtProject, err)
 	}
 
-	if dbtProject.Status.Phase != orchestrationv1alpha1.DbtProjectPhaseReady {
+	result, err := r.reconcileSchedules(ctx, &dbtProject, &activeRuns)
+	if invalid := (*blackoutsError)(nil); errors.As(err, &invalid) {
+		return ctrl.Result{}, r.invalidateSchedules(ctx, &dbtProject, err)
+	}
+	if err == nil {
+		err = r.reconcileUpstreamTriggers(ctx, &dbtProject, &activeRuns)
+	}
+	if err == nil {
+		err = r.reconcileGitChange(ctx, &dbtProject, &activeRuns)
+	}
+	// Check back while replaced runs stop so that the runs waiting for them
+	// start promptly.
+	if slices.ContainsFunc(activeRuns, stoppingRun) && (result.RequeueAfter == 0 || result.RequeueAfter > replaceRequeueInterval) {
+		result.RequeueAfter = replaceRequeueInterval
+	}
+	if err != nil {
+		log.Error(err, "Failed to schedule project")
+		dbtProject.Status.Phase = orchestrationv1alpha1.DbtProjectPhaseError
+		r.Status().Update(ctx, &dbtProject)
+		return ctrl.Result{RequeueAfter: 1 * time.Minute}, err
+	}
+
+	valid := meta.RemoveStatusCondition(&dbtProject.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)
+	if valid || dbtProject.Status.Phase != orchestrationv1alpha1.DbtProjectPhaseReady {
 		dbtProject.Status.Phase = orchestrationv1alpha1.DbtProjectPhaseReady
 		if err := r.Status().Update(ctx, &dbtProject); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
-func (r *DbtProjectReconciler) scheduleProject(ctx context.Context, project *orchestrationv1alpha1.DbtProject) error {
-	jobID := fmt.Sprintf("%s/%s", project.Namespace, project.Name)
+// reconcileSchedules starts the due ticks of each of the project's schedules
+// allowed by their catch-up, concurrency and blackout rules, records them in
+// the project status and asks to be requeued at the earliest next tick. All
+// scheduling state lives in the status, so a restarted or newly elected
+// manager picks up exactly where the previous one stopped.
+func (r *DbtProjectReconciler) reconcileSchedules(ctx context.Context, project *orchestrationv1alpha1.DbtProject, activeRuns *[]orchestrationv1alpha1.DbtRun) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
 
-	r.removeScheduledJob(types.NamespacedName{
-		Namespace: project.Namespace,
-		Name:      project.Name,
-	})
+	schedules, err := projectSchedules(project)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var blackouts blackoutRules
+	if len(schedules) > 0 {
+		if blackouts, err = r.getBlackouts(ctx, project); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := r.now()
+	changed := pruneScheduleStatuses(&project.Status, schedules)
+
+	var upcoming []time.Time
+	var requeueAt time.Time
+	for _, schedule := range schedules {
+		count := len(project.Status.Schedules)
+		entry := scheduleStatusFor(&project.Status, schedule.Name)
+		if len(project.Status.Schedules) != count {
+			changed = true
+		}
+
+		if schedule.Suspend {
+			if entry.NextScheduleTime != nil {
+				entry.NextScheduleTime = nil
+				changed = true
+			}
+			continue
+		}
+
+		started, next, err := r.startDueTicks(ctx, project, schedule, entry, activeRuns, blackouts, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		changed = changed || started
+
+		scheduleUpcoming, err := getUpcomingSchedules(project, schedule.Schedule, now, blackouts)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		var nextScheduleTime *metav1.Time
+		if len(scheduleUpcoming) > 0 {
+			nextScheduleTime = &metav1.Time{Time: scheduleUpcoming[0]}
+		}
+		if !equality.Semantic.DeepEqual(nextScheduleTime, entry.NextScheduleTime) {
+			entry.NextScheduleTime = nextScheduleTime
+			changed = true
+		}
+		upcoming = append(upcoming, scheduleUpcoming...)
+
+		if next.IsZero() {
+			log.Info("Schedule has no upcoming fire times", "schedule", schedule.Name, "cron", schedule.Schedule)
+		} else if requeueAt.IsZero() || next.Before(requeueAt) {
+			requeueAt = next
+		}
+	}
+
+	for _, entry := range project.Status.Schedules {
+		if entry.LastScheduledTime != nil && (project.Status.LastScheduledTime == nil ||
+			entry.LastScheduledTime.After(project.Status.LastScheduledTime.Time)) {
+			project.Status.LastScheduledTime = entry.LastScheduledTime.DeepCopy()
+			changed = true
+		}
+	}
+
+	if setUpcomingSchedules(&project.Status, upcoming) {
+		changed = true
+	}
+
+	if changed {
+		if err := r.Status().Update(ctx, project); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if requeueAt.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: requeueAt.Sub(now)}, nil
+}
 
-	entryID, err := r.Scheduler.AddFunc(project.Spec.Schedule, func() {
-		r.createScheduledRun(project)
+// startDueTicks starts the due ticks of a single schedule, keeping activeRuns
+// and the project's active run references up to date, and reports whether the
+// status changed along with the schedule's first fire time after now.
+func (r *DbtProjectReconciler) startDueTicks(ctx context.Context, project *orchestrationv1alpha1.DbtProject, schedule orchestrationv1alpha1.NamedSchedule, entry *orchestrationv1alpha1.ScheduleStatus, activeRuns *[]orchestrationv1alpha1.DbtRun, blackouts blackoutRules, now time.Time) (bool, time.Time, error) {
+	log := log.FromContext(ctx)
+
+	missed, next, err := getMissedSchedules(project, schedule.Schedule, entry.LastScheduledTime, now)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	concurrencyPolicy := project.Spec.ConcurrencyPolicy
+
+	changed := false
+	for _, scheduledTime := range missed {
+		// A late tick must not start inside a blackout either.
+		if source := blackouts.blockedBy(scheduledTime); source != "" {
+			r.skipTick(ctx, project, schedule.Name, entry, scheduledTime, orchestrationv1alpha1.ProjectReasonBlackout,
+				fmt.Sprintf("the tick falls in %s", source))
+			changed = true
+			continue
+		}
+		if source := blackouts.blockedBy(now); source != "" {
+			r.skipTick(ctx, project, schedule.Name, entry, scheduledTime, orchestrationv1alpha1.ProjectReasonBlackout,
+				fmt.Sprintf("it would start in %s", source))
+			changed = true
+			continue
+		}
+
+		admitted, err := r.admitRun(ctx, project, activeRuns)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if !admitted {
+			if concurrencyPolicy == orchestrationv1alpha1.ReplaceConcurrent {
+				// The tick starts once the replaced runs have stopped.
+				break
+			}
+			r.skipTick(ctx, project, schedule.Name, entry, scheduledTime, orchestrationv1alpha1.ProjectReasonConcurrencyForbidden,
+				"a run the project started is still active and concurrencyPolicy is Forbid")
+			changed = true
+			continue
+		}
+
+		run, err := r.createScheduledRun(ctx, project, schedule, scheduledTime)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		log.Info("Created scheduled run", "run", run.Name, "schedule", schedule.Name, "scheduledTime", scheduledTime)
+		*activeRuns = append(*activeRuns, *run)
+		project.Status.ActiveRuns = runReferences(*activeRuns)
+		entry.LastScheduledTime = &metav1.Time{Time: scheduledTime}
+		meta.SetStatusCondition(&project.Status.Conditions, metav1.Condition{
+			Type:               orchestrationv1alpha1.ProjectConditionTickSkipped,
+			Status:             metav1.ConditionFalse,
+			Reason:             orchestrationv1alpha1.ProjectReasonRunCreated,
+			Message:            fmt.Sprintf("Created run %s for %s", run.Name, formatTick(scheduledTime)),
+			ObservedGeneration: project.Generation,
+		})
+		changed = true
+	}
+
+	return changed, next, nil
+}
+
+// skipTick marks a due tick of a schedule as handled without starting a run
+// and records why on the project, both as the TickSkipped condition and as
+// an event.
+func (r *DbtProjectReconciler) skipTick(ctx context.Context, project *orchestrationv1alpha1.DbtProject, scheduleName string, entry *orchestrationv1alpha1.ScheduleStatus, scheduledTime time.Time, reason, detail string) {
+	message := fmt.Sprintf("Skipped run of schedule %s for %s: %s", scheduleName, formatTick(scheduledTime), detail)
+	log.FromContext(ctx).Info("Skipping scheduled run", "schedule", scheduleName, "scheduledTime", scheduledTime, "reason", reason, "detail", detail)
+	r.event(project, corev1.EventTypeNormal, "SkippedTick", message)
+
+	entry.LastScheduledTime = &metav1.Time{Time: scheduledTime}
+	meta.SetStatusCondition(&project.Status.Conditions, metav1.Condition{
+		Type:               orchestrationv1alpha1.ProjectConditionTickSkipped,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: project.Generation,
 	})
+}
+
+// reconcileUpstreamTriggers starts a run of the project once the upstream
+// projects named in triggers.afterProjects have succeeded since the last
+// triggered run, as required by afterProjectsPolicy. Under the Forbid and
+// Replace concurrency policies the trigger waits for active runs to finish,
+// or to stop once replaced, instead of being dropped.
+func (r *DbtProjectReconciler) reconcileUpstreamTriggers(ctx context.Context, project *orchestrationv1alpha1.DbtProject, activeRuns *[]orchestrationv1alpha1.DbtRun) error {
+	log := log.FromContext(ctx)
+
+	triggers := project.Spec.Triggers
+	if triggers == nil || len(triggers.AfterProjects) == 0 {
+		if meta.RemoveStatusCondition(&project.Status.Conditions, orchestrationv1alpha1.ProjectConditionUpstreamsPending) {
+			return r.Status().Update(ctx, project)
+		}
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               orchestrationv1alpha1.ProjectConditionUpstreamsPending,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: project.Generation,
+	}
+
+	var projects orchestrationv1alpha1.DbtProjectList
+	if err := r.List(ctx, &projects, client.InNamespace(project.Namespace)); err != nil {
+		return err
+	}
+	if cycle := upstreamCycle(projects.Items, project.Name); cycle != nil {
+		condition.Reason = orchestrationv1alpha1.ProjectReasonUpstreamCycle
+		condition.Message = fmt.Sprintf("%s runs after itself through afterProjects (%s); no run is triggered until the cycle is broken",
+			project.Name, strings.Join(cycle, " -> "))
+		if !meta.SetStatusCondition(&project.Status.Conditions, condition) {
+			return nil
+		}
+		r.event(project, corev1.EventTypeWarning, orchestrationv1alpha1.ProjectReasonUpstreamCycle, condition.Message)
+		return r.Status().Update(ctx, project)
+	}
+
+	since := project.CreationTimestamp.Time
+	if project.Status.LastTriggeredTime != nil {
+		since = project.Status.LastTriggeredTime.Time
+	}
+
+	var runs orchestrationv1alpha1.DbtRunList
+	if err := r.List(ctx, &runs, client.InNamespace(project.Namespace)); err != nil {
+		return err
+	}
+	successes := upstreamSuccesses(runs.Items, triggers.AfterProjects, since)
+
+	if pending := pendingUpstreams(triggers, successes); len(pending) > 0 {
+		condition.Reason = orchestrationv1alpha1.ProjectReasonWaitingForUpstreams
+		condition.Message = fmt.Sprintf("Waiting for %s to succeed", strings.Join(pending, ", "))
+		return r.setProjectCondition(ctx, project, condition)
+	}
+
+	if admitted, err := r.admitRun(ctx, project, activeRuns); err != nil || !admitted {
+		if err != nil {
+			return err
+		}
+		condition.Reason = orchestrationv1alpha1.ProjectReasonConcurrencyForbidden
+		condition.Message = fmt.Sprintf("Upstream projects succeeded; waiting for %d active run(s) to finish", len(concurrentRuns(*activeRuns)))
+		return r.setProjectCondition(ctx, project, condition)
+	}
+
+	var completed time.Time
+	var triggeredBy []string
+	for _, run := range successes {
+		triggeredBy = append(triggeredBy, run.Name)
+		if run.Status.CompletionTime.After(completed) {
+			completed = run.Status.CompletionTime.Time
+		}
+	}
+	slices.Sort(triggeredBy)
+
+	run, err := r.createUpstreamRun(ctx, project, completed, triggeredBy)
 	if err != nil {
-		return fmt.Errorf("failed to schedule job: %w", err)
+		return err
 	}
+	log.Info("Created run after upstream projects", "run", run.Name, "triggeredBy", triggeredBy)
 
-	scheduledJobs[jobID] = entryID
-	return nil
+	*activeRuns = append(*activeRuns, *run)
+	project.Status.ActiveRuns = runReferences(*activeRuns)
+	project.Status.LastTriggeredTime = &metav1.Time{Time: completed}
+	condition.Status = metav1.ConditionFalse
+	condition.Reason = orchestrationv1alpha1.ProjectReasonRunTriggered
+	condition.Message = fmt.Sprintf("Created run %s after %s", run.Name, strings.Join(triggeredBy, ", "))
+	meta.SetStatusCondition(&project.Status.Conditions, condition)
+	return r.Status().Update(ctx, project)
 }
 
-var scheduledJobs = make(map[string]cron.EntryID)
+// reconcileGitChange starts a run of the commit the GitPoller last resolved
+// for the project once it differs from the commit of its latest GitChange
+// run. The first commit resolved only sets the baseline.
+func (r *DbtProjectReconciler) reconcileGitChange(ctx context.Context, project *orchestrationv1alpha1.DbtProject, activeRuns *[]orchestrationv1alpha1.DbtRun) error {
+	git := projectGit(project)
+	commit := project.Status.ResolvedCommit
+	if git == nil || git.Poll == nil || commit == "" || commit == project.Status.TriggeredCommit {
+		return nil
+	}
 
-func (r *DbtProjectReconciler) removeScheduledJob(key types.NamespacedName) {
-	jobID := fmt.Sprintf("%s/%s", key.Namespace, key.Name)
-	if entryID, exists := scheduledJobs[jobID]; exists {
-		r.Scheduler.Remove(entryID)
-		delete(scheduledJobs, jobID)
+	previous := project.Status.TriggeredCommit
+	if previous != "" {
+		admitted, err := r.admitRun(ctx, project, activeRuns)
+		if err != nil {
+			return err
+		}
+		if !admitted {
+			// The change is picked up once the active runs have finished.
+			return r.setProjectCondition(ctx, project, metav1.Condition{
+				Type:   orchestrationv1alpha1.ProjectConditionRefResolved,
+				Status: metav1.ConditionTrue,
+				Reason: orchestrationv1alpha1.ProjectReasonPollSucceeded,
+				Message: fmt.Sprintf("%s moved to %s; waiting for %d active run(s) to finish",
+					getGitRef(git.Ref), commit, len(concurrentRuns(*activeRuns))),
+				ObservedGeneration: project.Generation,
+			})
+		}
+
+		run, err := r.createGitChangeRun(ctx, project, commit)
+		if err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Created run for new commit", "run", run.Name, "commit", commit, "previous", previous)
+		*activeRuns = append(*activeRuns, *run)
+		project.Status.ActiveRuns = runReferences(*activeRuns)
 	}
+
+	project.Status.TriggeredCommit = commit
+	return r.Status().Update(ctx, project)
 }
 
-func (r *DbtProjectReconciler) createScheduledRun(project *orchestrationv1alpha1.DbtProject) {
-	ctx := context.Background()
-	log := log.FromContext(ctx)
+// admitRun applies the project's concurrency policy to a run it is about to
+// start from a schedule or a trigger. Under Forbid it reports false while
+// runs the project started are active. Under Replace it cancels them and
+// reports false until they have stopped, so that the new run never overlaps
+// them; Reconcile checks back while they stop.
+func (r *DbtProjectReconciler) admitRun(ctx context.Context, project *orchestrationv1alpha1.DbtProject, activeRuns *[]orchestrationv1alpha1.DbtRun) (bool, error) {
+	if len(concurrentRuns(*activeRuns)) == 0 {
+		return true, nil
+	}
+
+	switch project.Spec.ConcurrencyPolicy {
+	case orchestrationv1alpha1.ForbidConcurrent:
+		return false, nil
+	case orchestrationv1alpha1.ReplaceConcurrent:
+		for i := range *activeRuns {
+			run := &(*activeRuns)[i]
+			if !startedByProject(*run) || run.Spec.Cancel {
+				continue
+			}
+			if err := replaceRun(ctx, r.Client, run); err != nil {
+				return false, err
+			}
+			log.FromContext(ctx).Info("Replacing active run", "run", run.Name)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// invalidateSchedules puts a project whose schedules cannot be evaluated into
+// the Error phase with the ScheduleInvalid condition and a warning event. It
+// is not requeued: fixing the spec, or creating or fixing a referenced
+// calendar, triggers the next reconcile.
+func (r *DbtProjectReconciler) invalidateSchedules(ctx context.Context, project *orchestrationv1alpha1.DbtProject, cause error) error {
+	log.FromContext(ctx).Info("Project has an invalid schedule", "error", cause.Error())
+	reason := orchestrationv1alpha1.ProjectReasonInvalidSchedule
+	if invalid := (*blackoutsError)(nil); errors.As(cause, &invalid) {
+		reason = invalid.reason
+	}
+	changed := meta.SetStatusCondition(&project.Status.Conditions, metav1.Condition{
+		Type:               orchestrationv1alpha1.ProjectConditionScheduleInvalid,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            cause.Error(),
+		ObservedGeneration: project.Generation,
+	})
+	if !changed && project.Status.Phase == orchestrationv1alpha1.DbtProjectPhaseError {
+		return nil
+	}
+	project.Status.Phase = orchestrationv1alpha1.DbtProjectPhaseError
+	r.event(project, corev1.EventTypeWarning, reason, cause.Error())
+	return r.Status().Update(ctx, project)
+}
+
+// setProjectCondition sets a condition on the project and updates its status
+// if that changed anything.
+func (r *DbtProjectReconciler) setProjectCondition(ctx context.Context, project *orchestrationv1alpha1.DbtProject, condition metav1.Condition) error {
+	if !meta.SetStatusCondition(&project.Status.Conditions, condition) {
+		return nil
+	}
+	return r.Status().Update(ctx, project)
+}
+
+// getBlackouts collects the project's own blackout windows and those of the
+// DbtCalendars it references.
+func (r *DbtProjectReconciler) getBlackouts(ctx context.Context, project *orchestrationv1alpha1.DbtProject) (blackoutRules, error) {
+	blackouts, err := newBlackoutWindowRules("project", project.Spec.BlackoutWindows, project.Spec.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range project.Spec.Calendars {
+		var calendar orchestrationv1alpha1.DbtCalendar
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, &calendar); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, &blackoutsError{
+					reason: orchestrationv1alpha1.ProjectReasonCalendarNotFound,
+					err:    fmt.Errorf("DbtCalendar %q not found", name),
+				}
+			}
+			return nil, fmt.Errorf("failed to get DbtCalendar %q: %w", name, err)
+		}
+		rules, err := newCalendarRules(&calendar)
+		if err != nil {
+			return nil, &blackoutsError{reason: orchestrationv1alpha1.ProjectReasonInvalidCalendar, err: err}
+		}
+		blackouts = append(blackouts, rules...)
+	}
+
+	return blackouts, nil
+}
+
+func (r *DbtProjectReconciler) createScheduledRun(ctx context.Context, project *orchestrationv1alpha1.DbtProject, schedule orchestrationv1alpha1.NamedSchedule, scheduledTime time.Time) (*orchestrationv1alpha1.DbtRun, error) {
+	// A schedule's own invocation or steps replace the project's commands,
+	// as its commands do.
+	commands := schedule.Commands
+	if len(commands) == 0 && schedule.Invocation == nil && len(schedule.Steps) == 0 {
+		commands = project.Spec.Commands
+	}
 
+	return r.createProjectRun(ctx, project, scheduledRunName(project, schedule.Name, scheduledTime),
+		map[string]string{scheduleLabel: schedule.Name},
+		map[string]string{scheduledTimeAnnotation: formatTick(scheduledTime)},
+		orchestrationv1alpha1.DbtRunSpec{
+			Type:       orchestrationv1alpha1.RunTypeScheduled,
+			Commands:   commands,
+			Invocation: schedule.Invocation,
+			Steps:      schedule.Steps,
+		})
+}
+
+func (r *DbtProjectReconciler) createUpstreamRun(ctx context.Context, project *orchestrationv1alpha1.DbtProject, completed time.Time, triggeredBy []string) (*orchestrationv1alpha1.DbtRun, error) {
+	return r.createProjectRun(ctx, project, upstreamRunName(project, completed), nil,
+		map[string]string{triggeredByAnnotation: strings.Join(triggeredBy, ",")},
+		orchestrationv1alpha1.DbtRunSpec{
+			Type:     orchestrationv1alpha1.RunTypeUpstream,
+			Commands: project.Spec.Commands,
+		})
+}
+
+func (r *DbtProjectReconciler) createGitChangeRun(ctx context.Context, project *orchestrationv1alpha1.DbtProject, commit string) (*orchestrationv1alpha1.DbtRun, error) {
+	return r.createProjectRun(ctx, project, boundedName(fmt.Sprintf("%s-git-%s", project.Name, commit[:12]), maxRunNameLength), nil,
+		map[string]string{commitAnnotation: commit},
+		orchestrationv1alpha1.DbtRunSpec{
+			Type:     orchestrationv1alpha1.RunTypeGitChange,
+			Commands: project.Spec.Commands,
+		})
+}
+
+// createProjectRun creates a run of the project with spec under name, which
+// is derived from what started it, with the labels and annotations recording
+// that. If a previous reconcile already created the run, it returns that one
+// instead, so that every schedule tick or trigger starts at most one run.
+func (r *DbtProjectReconciler) createProjectRun(ctx context.Context, project *orchestrationv1alpha1.DbtProject, name string, labels, annotations map[string]string, spec orchestrationv1alpha1.DbtRunSpec) (*orchestrationv1alpha1.DbtRun, error) {
+	spec.ProjectRef = corev1.LocalObjectReference{Name: project.Name}
 	run := &orchestrationv1alpha1.DbtRun{
 		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: fmt.Sprintf("%s-", project.Name),
-			Namespace:    project.Namespace,
-		},
-		Spec: orchestrationv1alpha1.DbtRunSpec{
-			ProjectRef: corev1.LocalObjectReference{
-				Name: project.Name,
-			},
-			Type:     orchestrationv1alpha1.RunTypeScheduled,
-			Commands: project.Spec.Commands,
+			Name:        name,
+			Namespace:   project.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
 		},
+		Spec: spec,
 	}
 
 	if err := controllerutil.SetControllerReference(project, run, r.Scheme); err != nil {
-		log.Error(err, "Failed to set controller reference")
-		return
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
 	}
 
 	if err := r.Create(ctx, run); err != nil {
-		log.Error(err, "Failed to create scheduled run")
-		return
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create %s run: %w", spec.Type, err)
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+			return nil, err
+		}
 	}
 
-	now := metav1.Now()
-	project.Status.LastScheduledTime = &now
-	if err := r.Status().Update(ctx, project); err != nil {
-		log.Error(err, "Failed to update project status")
+	return run, nil
+}
+
+func runReferences(runs []orchestrationv1alpha1.DbtRun) []corev1.ObjectReference {
+	var refs []corev1.ObjectReference
+	for _, run := range runs {
+		refs = append(refs, corev1.ObjectReference{
+			Kind:       "DbtRun",
+			APIVersion: orchestrationv1alpha1.GroupVersion.String(),
+			Name:       run.Name,
+			Namespace:  run.Namespace,
+			UID:        run.UID,
+		})
 	}
+	return refs
 }
 
-func (r *DbtProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	if r.Scheduler == nil {
-		r.Scheduler = cron.New(cron.WithSeconds())
-		r.Scheduler.Start()
+// event records an event on the project when the reconciler has a Recorder.
+func (r *DbtProjectReconciler) event(project *orchestrationv1alpha1.DbtProject, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
 	}
+	r.Recorder.Event(project, eventType, reason, message)
+}
+
+func (r *DbtProjectReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
 
+func (r *DbtProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&orchestrationv1alpha1.DbtProject{}).
 		Owns(&orchestrationv1alpha1.DbtRun{}).
@@ -164,6 +635,15 @@ func (r *DbtProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.findProjectForRun),
 			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
 		).
+		Watches(
+			&orchestrationv1alpha1.DbtProject{},
+			handler.EnqueueRequestsFromMapFunc(r.findProjectsAfterProject),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&orchestrationv1alpha1.DbtCalendar{},
+			handler.EnqueueRequestsFromMapFunc(r.findProjectsForCalendar),
+		).
 		Complete(r)
 }
 
@@ -173,7 +653,7 @@ func (r *DbtProjectReconciler) findProjectForRun(ctx context.Context, obj client
 		return []reconcile.Request{}
 	}
 
-	return []reconcile.Request{
+	requests := []reconcile.Request{
 		{
 			NamespacedName: types.NamespacedName{
 				Name:      run.Spec.ProjectRef.Name,
@@ -181,4 +661,60 @@ func (r *DbtProjectReconciler) findProjectForRun(ctx context.Context, obj client
 			},
 		},
 	}
+
+	if run.Status.Phase != orchestrationv1alpha1.RunPhaseSucceeded {
+		return requests
+	}
+
+	// A successful run may trigger downstream projects.
+	return append(requests, r.findDownstreamProjects(ctx, run.Namespace, run.Spec.ProjectRef.Name)...)
+}
+
+// findProjectsAfterProject enqueues the projects that run after a project
+// whose spec changed, as it may have closed or broken a cycle of theirs.
+func (r *DbtProjectReconciler) findProjectsAfterProject(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.findDownstreamProjects(ctx, obj.GetNamespace(), obj.GetName())
+}
+
+// findDownstreamProjects returns requests for the projects in namespace that
+// name upstream in triggers.afterProjects.
+func (r *DbtProjectReconciler) findDownstreamProjects(ctx context.Context, namespace, upstream string) []reconcile.Request {
+	var projects orchestrationv1alpha1.DbtProjectList
+	if err := r.List(ctx, &projects, client.InNamespace(namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list downstream projects", "project", upstream)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, project := range projects.Items {
+		if project.Spec.Triggers != nil && slices.Contains(project.Spec.Triggers.AfterProjects, upstream) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      project.Name,
+					Namespace: project.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *DbtProjectReconciler) findProjectsForCalendar(ctx context.Context, obj client.Object) []reconcile.Request {
+	var projects orchestrationv1alpha1.DbtProjectList
+	if err := r.List(ctx, &projects); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list projects for calendar", "calendar", obj.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, project := range projects.Items {
+		if slices.Contains(project.Spec.Calendars, obj.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      project.Name,
+					Namespace: project.Namespace,
+				},
+			})
+		}
+	}
+	return requests
 }
@@ -18,11 +18,23 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,7 +63,9 @@ var _ = Describe("DbtProject Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: orchestrationv1alpha1.DbtProjectSpec{
+						Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -80,5 +94,907 @@ var _ = Describe("DbtProject Controller", func() {
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
+
+		It("should report an invalid schedule without an event recorder", func() {
+			Expect(k8sClient.Get(ctx, typeNamespacedName, dbtproject)).To(Succeed())
+			dbtproject.Spec.Schedule = "0 * * * *"
+			dbtproject.Spec.TimeZone = "Europe/Atlantis"
+			Expect(k8sClient.Update(ctx, dbtproject)).To(Succeed())
+
+			controllerReconciler := &DbtProjectReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, dbtproject)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(dbtproject.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)).To(BeTrue())
+		})
+	})
+
+	Context("When the project has a schedule", func() {
+		const resourceName = "scheduled-project"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		lastScheduled := time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			By("creating an hourly DbtProject that last fired an hour ago")
+			resource := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git:      orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+					Schedule: "0 0 * * * *",
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.LastScheduledTime = &metav1.Time{Time: lastScheduled}
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &orchestrationv1alpha1.DbtRun{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		It("should create exactly one run per tick and requeue for the next one", func() {
+			now := time.Date(2025, 1, 1, 12, 0, 30, 0, time.UTC)
+			controllerReconciler := &DbtProjectReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(now),
+			}
+
+			By("reconciling twice, as a restarted or second manager would")
+			for range 2 {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(59*time.Minute + 30*time.Second))
+			}
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(HaveLen(1))
+			Expect(runs.Items[0].Spec.Type).To(Equal(orchestrationv1alpha1.RunTypeScheduled))
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.LastScheduledTime.Time).To(BeTemporally("==", lastScheduled.Add(time.Hour)))
+			Expect(project.Status.NextScheduleTime.Time).To(BeTemporally("==", lastScheduled.Add(2*time.Hour)))
+			Expect(project.Status.UpcomingScheduleTimes).To(HaveLen(upcomingScheduleCount))
+			Expect(project.Status.UpcomingScheduleTimes[2].Time).To(BeTemporally("==", lastScheduled.Add(4*time.Hour)))
+		})
+
+		It("should catch up on every missed tick within the starting deadline", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.CatchUp = orchestrationv1alpha1.CatchUpAll
+			project.Spec.StartingDeadlineSeconds = ptr.To(int64(90 * 60))
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			controllerReconciler := &DbtProjectReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(lastScheduled.Add(3*time.Hour + 30*time.Second)),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("skipping the 12:00 tick, which is past the deadline")
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			scheduledTimes := []string{}
+			for _, run := range runs.Items {
+				scheduledTimes = append(scheduledTimes, run.Annotations[scheduledTimeAnnotation])
+			}
+			Expect(scheduledTimes).To(ConsistOf("2025-01-01T13:00:00Z", "2025-01-01T14:00:00Z"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.LastScheduledTime.Time).To(BeTemporally("==", lastScheduled.Add(3*time.Hour)))
+		})
+
+		It("should skip ticks inside the project's blackout windows", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.BlackoutWindows = []orchestrationv1alpha1.BlackoutWindow{{
+				Name:     "warehouse-maintenance",
+				Schedule: "45 11 * * *",
+				Duration: &metav1.Duration{Duration: 90 * time.Minute},
+			}}
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("warehouse-maintenance")))
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionTickSkipped)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonBlackout))
+			Expect(project.Status.LastScheduledTime.Time).To(BeTemporally("==", lastScheduled.Add(time.Hour)))
+
+			By("publishing 14:00 as the next fire time, since 13:00 is still inside the window")
+			Expect(project.Status.NextScheduleTime.Time).To(BeTemporally("==", lastScheduled.Add(3*time.Hour)))
+		})
+
+		It("should leave holidays from referenced calendars out of the upcoming fire times", func() {
+			calendar := &orchestrationv1alpha1.DbtCalendar{
+				ObjectMeta: metav1.ObjectMeta{Name: "financial-close"},
+				Spec: orchestrationv1alpha1.DbtCalendarSpec{
+					TimeZone: "America/New_York",
+					Dates:    []string{"2025-01-01"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, calendar)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, calendar)).To(Succeed())
+			})
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Calendars = []string{calendar.Name}
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("resuming at 05:00 UTC, which is midnight in New York")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.UpcomingScheduleTimes).To(HaveLen(upcomingScheduleCount))
+			Expect(project.Status.NextScheduleTime.Time).To(BeTemporally("==", time.Date(2025, 1, 2, 5, 0, 0, 0, time.UTC)))
+		})
+
+		It("should report an unknown time zone and resume once it is fixed", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.TimeZone = "Europe/Atlantis"
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Europe/Atlantis")))
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.Phase).To(Equal(orchestrationv1alpha1.DbtProjectPhaseError))
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonInvalidSchedule))
+			Expect(condition.Message).To(ContainSubstring("Europe/Atlantis"))
+
+			By("scheduling again once the time zone is fixed")
+			project.Spec.TimeZone = "Europe/Berlin"
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(HaveLen(1))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.Phase).To(Equal(orchestrationv1alpha1.DbtProjectPhaseReady))
+			Expect(meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)).To(BeNil())
+		})
+
+		It("should hold the schedules while a referenced calendar is missing", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Calendars = []string{"quarter-close"}
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(recorder.Events).To(Receive(ContainSubstring("quarter-close")))
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.Phase).To(Equal(orchestrationv1alpha1.DbtProjectPhaseError))
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonCalendarNotFound))
+
+			By("scheduling again once the calendar exists")
+			calendar := &orchestrationv1alpha1.DbtCalendar{ObjectMeta: metav1.ObjectMeta{Name: "quarter-close"}}
+			Expect(k8sClient.Create(ctx, calendar)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, calendar)).To(Succeed())
+			})
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(HaveLen(1))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.Phase).To(Equal(orchestrationv1alpha1.DbtProjectPhaseReady))
+			Expect(meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionScheduleInvalid)).To(BeNil())
+		})
+
+		It("should run named schedules alongside the default one with their own commands", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Schedules = []orchestrationv1alpha1.NamedSchedule{{
+				Name:     "snapshot",
+				Schedule: "0 12 * * *",
+				Commands: []string{"snapshot"},
+			}}
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+			project.Status.Schedules = []orchestrationv1alpha1.ScheduleStatus{{
+				Name:              "snapshot",
+				LastScheduledTime: &metav1.Time{Time: lastScheduled.Add(-23 * time.Hour)},
+			}}
+			Expect(k8sClient.Status().Update(ctx, project)).To(Succeed())
+
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(59*time.Minute + 30*time.Second))
+
+			tick := lastScheduled.Add(time.Hour)
+			snapshot := &orchestrationv1alpha1.DbtRun{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      scheduledRunName(project, "snapshot", tick),
+				Namespace: "default",
+			}, snapshot)).To(Succeed())
+			Expect(snapshot.Labels).To(HaveKeyWithValue(scheduleLabel, "snapshot"))
+			Expect(snapshot.Spec.Commands).To(Equal([]string{"snapshot"}))
+
+			hourly := &orchestrationv1alpha1.DbtRun{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      scheduledRunName(project, defaultScheduleName, tick),
+				Namespace: "default",
+			}, hourly)).To(Succeed())
+			Expect(hourly.Labels).To(HaveKeyWithValue(scheduleLabel, defaultScheduleName))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.Schedules).To(HaveLen(2))
+			entry := scheduleStatusFor(&project.Status, "snapshot")
+			Expect(entry.LastScheduledTime.Time).To(BeTemporally("==", tick))
+			Expect(entry.NextScheduleTime.Time).To(BeTemporally("==", tick.Add(24*time.Hour)))
+		})
+
+		It("should let named schedules override the project's steps with an invocation or steps", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Steps = []orchestrationv1alpha1.DbtStep{
+				{Name: "deps", Commands: []string{"deps"}},
+				{Name: "build", Commands: []string{"build"}},
+			}
+			project.Spec.Schedules = []orchestrationv1alpha1.NamedSchedule{
+				{
+					Name:     "commands",
+					Schedule: "0 12 * * *",
+					Commands: []string{"snapshot"},
+				},
+				{
+					Name:       "invocation",
+					Schedule:   "0 12 * * *",
+					Commands:   []string{"snapshot"},
+					Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build", Select: []string{"tag:nightly"}},
+				},
+			}
+			By("rejecting a schedule with both commands and an invocation")
+			Expect(k8sClient.Update(ctx, project)).To(MatchError(ContainSubstring("commands and invocation are mutually exclusive")))
+
+			project.Spec.Schedules[1].Commands = nil
+			project.Spec.Schedules = append(project.Spec.Schedules, orchestrationv1alpha1.NamedSchedule{
+				Name:     "steps",
+				Schedule: "0 12 * * *",
+				Steps:    []orchestrationv1alpha1.DbtStep{{Name: "docs", Commands: []string{"docs", "generate"}}},
+			})
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+			for _, schedule := range project.Spec.Schedules {
+				project.Status.Schedules = append(project.Status.Schedules, orchestrationv1alpha1.ScheduleStatus{
+					Name:              schedule.Name,
+					LastScheduledTime: &metav1.Time{Time: lastScheduled.Add(-23 * time.Hour)},
+				})
+			}
+			Expect(k8sClient.Status().Update(ctx, project)).To(Succeed())
+
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+				Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			tick := lastScheduled.Add(time.Hour)
+			resolved := func(schedule string) ([]string, []orchestrationv1alpha1.DbtStep) {
+				run := &orchestrationv1alpha1.DbtRun{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Name:      scheduledRunName(project, schedule, tick),
+					Namespace: "default",
+				}, run)).To(Succeed())
+				args, _, steps, err := resolveCommands(run, project)
+				Expect(err).NotTo(HaveOccurred())
+				return args, steps
+			}
+			args, steps := resolved("commands")
+			Expect(args).To(Equal([]string{"snapshot"}))
+			Expect(steps).To(BeEmpty())
+			args, steps = resolved("invocation")
+			Expect(args).To(Equal([]string{"build", "--select", "tag:nightly"}))
+			Expect(steps).To(BeEmpty())
+			args, steps = resolved("steps")
+			Expect(args).To(BeEmpty())
+			Expect(steps).To(ConsistOf(HaveField("Name", "docs")))
+			args, steps = resolved(defaultScheduleName)
+			Expect(args).To(BeEmpty())
+			Expect(steps).To(HaveLen(2))
+		})
+
+		Context("while a previous run is still active", func() {
+			var previous *orchestrationv1alpha1.DbtRun
+
+			BeforeEach(func() {
+				previous = &orchestrationv1alpha1.DbtRun{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "scheduled-project-previous",
+						Namespace: "default",
+					},
+					Spec: orchestrationv1alpha1.DbtRunSpec{
+						ProjectRef: corev1.LocalObjectReference{Name: resourceName},
+						Type:       orchestrationv1alpha1.RunTypeScheduled,
+					},
+				}
+				Expect(k8sClient.Create(ctx, previous)).To(Succeed())
+				previous.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
+				Expect(k8sClient.Status().Update(ctx, previous)).To(Succeed())
+			})
+
+			reconcileWithPolicy := func(policy orchestrationv1alpha1.ConcurrencyPolicy) *record.FakeRecorder {
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				project.Spec.ConcurrencyPolicy = policy
+				Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+				recorder := record.NewFakeRecorder(10)
+				controllerReconciler := &DbtProjectReconciler{
+					Client:   k8sClient,
+					Scheme:   k8sClient.Scheme(),
+					Recorder: recorder,
+					Clock:    clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 30*time.Second)),
+				}
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return recorder
+			}
+
+			It("should skip the tick under Forbid", func() {
+				recorder := reconcileWithPolicy(orchestrationv1alpha1.ForbidConcurrent)
+				Expect(recorder.Events).To(Receive(ContainSubstring("SkippedTick")))
+
+				var runs orchestrationv1alpha1.DbtRunList
+				Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+				Expect(runs.Items).To(HaveLen(1))
+
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				Expect(project.Status.ActiveRuns).To(HaveLen(1))
+				Expect(project.Status.ActiveRuns[0].Name).To(Equal(previous.Name))
+			})
+
+			It("should not let a manual run block the tick under Forbid", func() {
+				previous.Spec.Type = orchestrationv1alpha1.RunTypeManual
+				Expect(k8sClient.Update(ctx, previous)).To(Succeed())
+
+				reconcileWithPolicy(orchestrationv1alpha1.ForbidConcurrent)
+
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				Expect(project.Status.ActiveRuns).To(HaveLen(2))
+				Expect(project.Status.ActiveRuns[1].Name).To(Equal(scheduledRunName(project, defaultScheduleName, lastScheduled.Add(time.Hour))))
+			})
+
+			It("should not cancel a manual run under Replace", func() {
+				previous.Spec.Type = orchestrationv1alpha1.RunTypeManual
+				Expect(k8sClient.Update(ctx, previous)).To(Succeed())
+
+				reconcileWithPolicy(orchestrationv1alpha1.ReplaceConcurrent)
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(previous), previous)).To(Succeed())
+				Expect(previous.Spec.Cancel).To(BeFalse())
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				Expect(project.Status.ActiveRuns).To(HaveLen(2))
+			})
+
+			It("should cancel the active run under Replace and start the tick once it has stopped", func() {
+				reconcileWithPolicy(orchestrationv1alpha1.ReplaceConcurrent)
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(previous), previous)).To(Succeed())
+				Expect(previous.Spec.Cancel).To(BeTrue())
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				tick := types.NamespacedName{
+					Name:      scheduledRunName(project, defaultScheduleName, lastScheduled.Add(time.Hour)),
+					Namespace: "default",
+				}
+				Expect(k8sClient.Get(ctx, tick, &orchestrationv1alpha1.DbtRun{})).To(Satisfy(errors.IsNotFound))
+				Expect(project.Status.ActiveRuns).To(HaveLen(1))
+				Expect(project.Status.Schedules[0].LastScheduledTime.Time).To(BeTemporally("==", lastScheduled))
+
+				By("checking back while the replaced run stops")
+				controllerReconciler := &DbtProjectReconciler{
+					Client: k8sClient,
+					Scheme: k8sClient.Scheme(),
+					Clock:  clocktesting.NewFakePassiveClock(lastScheduled.Add(time.Hour + 35*time.Second)),
+				}
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(replaceRequeueInterval))
+				Expect(k8sClient.Get(ctx, tick, &orchestrationv1alpha1.DbtRun{})).To(Satisfy(errors.IsNotFound))
+
+				runReconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+				_, err = runReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(previous)})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(previous), previous)).To(Succeed())
+				Expect(previous.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+				condition := meta.FindStatusCondition(previous.Status.Conditions, orchestrationv1alpha1.RunConditionComplete)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Reason).To(Equal(orchestrationv1alpha1.RunReasonReplaced))
+
+				By("starting the tick once the replaced run has stopped")
+				result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).NotTo(Equal(replaceRequeueInterval))
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				Expect(project.Status.ActiveRuns).To(HaveLen(1))
+				Expect(project.Status.ActiveRuns[0].Name).To(Equal(tick.Name))
+			})
+
+			It("should let a webhook run block the tick under Forbid", func() {
+				previous.Spec.Type = orchestrationv1alpha1.RunTypeWebhook
+				Expect(k8sClient.Update(ctx, previous)).To(Succeed())
+
+				recorder := reconcileWithPolicy(orchestrationv1alpha1.ForbidConcurrent)
+				Expect(recorder.Events).To(Receive(ContainSubstring("SkippedTick")))
+			})
+		})
+	})
+
+	Context("When the project runs after upstream projects", func() {
+		const resourceName = "marketing"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("creating a DbtProject that runs after core and finance")
+			resource := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/marketing.git"},
+					Triggers: &orchestrationv1alpha1.ProjectTriggers{
+						AfterProjects: []string{"core", "finance"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &orchestrationv1alpha1.DbtRun{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		succeed := func(project string, completed time.Time) {
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("%s-%d", project, completed.Unix()),
+					Namespace: "default",
+				},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project},
+					Type:       orchestrationv1alpha1.RunTypeManual,
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			run.Status.Phase = orchestrationv1alpha1.RunPhaseSucceeded
+			run.Status.CompletionTime = &metav1.Time{Time: completed}
+			Expect(k8sClient.Status().Update(ctx, run)).To(Succeed())
+		}
+
+		triggeredRuns := func() []orchestrationv1alpha1.DbtRun {
+			controllerReconciler := &DbtProjectReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			var triggered []orchestrationv1alpha1.DbtRun
+			for _, run := range runs.Items {
+				if run.Spec.ProjectRef.Name == resourceName {
+					triggered = append(triggered, run)
+				}
+			}
+			return triggered
+		}
+
+		It("should run once after any upstream succeeds", func() {
+			completed := time.Now().Add(time.Minute).Truncate(time.Second)
+			succeed("core", completed)
+
+			By("reconciling twice, as a restarted or second manager would")
+			Expect(triggeredRuns()).To(HaveLen(1))
+			runs := triggeredRuns()
+			Expect(runs).To(HaveLen(1))
+			Expect(runs[0].Spec.Type).To(Equal(orchestrationv1alpha1.RunTypeUpstream))
+			Expect(runs[0].Annotations).To(HaveKeyWithValue(triggeredByAnnotation, fmt.Sprintf("core-%d", completed.Unix())))
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.LastTriggeredTime.Time).To(BeTemporally("==", completed))
+		})
+
+		It("should wait for all upstreams under the All policy", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Triggers.AfterProjectsPolicy = orchestrationv1alpha1.UpstreamAll
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			completed := time.Now().Add(time.Minute).Truncate(time.Second)
+			succeed("core", completed)
+			Expect(triggeredRuns()).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionUpstreamsPending)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(ContainSubstring("finance"))
+
+			succeed("finance", completed.Add(time.Minute))
+			Expect(triggeredRuns()).To(HaveLen(1))
+		})
+
+		It("should not trigger runs through a cycle of projects", func() {
+			core := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "core", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/core.git"},
+					Triggers: &orchestrationv1alpha1.ProjectTriggers{
+						AfterProjects: []string{"marketing"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, core)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, core)).To(Succeed())
+			})
+			succeed("core", time.Now().Add(time.Minute).Truncate(time.Second))
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &DbtProjectReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(triggeredRuns()).To(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring("UpstreamCycle")))
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionUpstreamsPending)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonUpstreamCycle))
+			Expect(condition.Message).To(ContainSubstring("marketing -> core -> marketing"))
+
+			By("triggering again once the cycle is broken")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(core), core)).To(Succeed())
+			core.Spec.Triggers = nil
+			Expect(k8sClient.Update(ctx, core)).To(Succeed())
+			Expect(controllerReconciler.findProjectsAfterProject(ctx, core)).To(ConsistOf(
+				reconcile.Request{NamespacedName: typeNamespacedName}))
+			Expect(triggeredRuns()).To(HaveLen(1))
+		})
+
+		It("should wait for a replaced webhook run to stop under Replace", func() {
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.ConcurrencyPolicy = orchestrationv1alpha1.ReplaceConcurrent
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			webhookRun := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "marketing-webhook-x7k2p", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: resourceName},
+					Type:       orchestrationv1alpha1.RunTypeWebhook,
+				},
+			}
+			Expect(k8sClient.Create(ctx, webhookRun)).To(Succeed())
+			webhookRun.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
+			Expect(k8sClient.Status().Update(ctx, webhookRun)).To(Succeed())
+
+			succeed("core", time.Now().Add(time.Minute).Truncate(time.Second))
+			Expect(triggeredRuns()).To(ConsistOf(HaveField("Name", webhookRun.Name)))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(webhookRun), webhookRun)).To(Succeed())
+			Expect(webhookRun.Spec.Cancel).To(BeTrue())
+
+			runReconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			_, err := runReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(webhookRun)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(triggeredRuns()).To(ContainElement(HaveField("Spec.Type", orchestrationv1alpha1.RunTypeUpstream)))
+		})
+	})
+
+	Context("When the project polls its git repository", func() {
+		const resourceName = "polled-project"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		var workTree string
+		git := func(dir string, args ...string) string {
+			cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+		push := func(message string) string {
+			git(workTree, "commit", "--allow-empty", "-q", "-m", message)
+			git(workTree, "push", "-q", "origin", "HEAD:main")
+			return git(workTree, "rev-parse", "HEAD")
+		}
+
+		BeforeEach(func() {
+			By("creating a local bare repository with a main branch")
+			dir := GinkgoT().TempDir()
+			remote := filepath.Join(dir, "analytics.git")
+			workTree = filepath.Join(dir, "work")
+			git(dir, "init", "-q", "--bare", remote)
+			git(dir, "init", "-q", workTree)
+			git(workTree, "remote", "add", "origin", remote)
+			push("initial")
+
+			resource := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{
+						Repository: remote,
+						Ref:        "main",
+						Poll:       &orchestrationv1alpha1.GitPoll{Interval: &metav1.Duration{Duration: time.Minute}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &orchestrationv1alpha1.DbtRun{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		pollAt := func(now time.Time) *orchestrationv1alpha1.DbtProject {
+			poller := &GitPoller{Client: k8sClient, Clock: clocktesting.NewFakePassiveClock(now)}
+			Expect(poller.Poll(ctx, typeNamespacedName)).To(Succeed())
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			return project
+		}
+
+		reconcileProject := func() {
+			controllerReconciler := &DbtProjectReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("should start a run when the branch moves to a new commit", func() {
+			By("recording the first commit seen without starting a run")
+			project := pollAt(start)
+			Expect(project.Status.ResolvedCommit).To(Equal(git(workTree, "rev-parse", "HEAD")))
+			Expect(project.Status.LastPollTime.Time).To(BeTemporally("==", start))
+			Expect(meta.IsStatusConditionTrue(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionRefResolved)).To(BeTrue())
+			reconcileProject()
+
+			var runs orchestrationv1alpha1.DbtRunList
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(BeEmpty())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.TriggeredCommit).To(Equal(project.Status.ResolvedCommit))
+
+			commit := push("add orders model")
+
+			By("waiting for the poll interval")
+			Expect(gitPollDue(project, start.Add(20*time.Second))).To(BeFalse())
+			Expect(gitPollDue(project, start.Add(time.Minute))).To(BeTrue())
+
+			project = pollAt(start.Add(time.Minute))
+			Expect(project.Status.ResolvedCommit).To(Equal(commit))
+			reconcileProject()
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(HaveLen(1))
+			Expect(runs.Items[0].Spec.Type).To(Equal(orchestrationv1alpha1.RunTypeGitChange))
+			Expect(runs.Items[0].Annotations).To(HaveKeyWithValue(commitAnnotation, commit))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(project.Status.TriggeredCommit).To(Equal(commit))
+
+			By("starting one run per commit")
+			reconcileProject()
+			Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+			Expect(runs.Items).To(HaveLen(1))
+		})
+
+		It("should report failed polls without touching the resolved commit", func() {
+			commit := pollAt(start).Status.ResolvedCommit
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Git.Repository = filepath.Join(GinkgoT().TempDir(), "missing.git")
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			project = pollAt(start.Add(time.Minute))
+			Expect(project.Status.ResolvedCommit).To(Equal(commit))
+			Expect(project.Status.LastPollTime.Time).To(BeTemporally("==", start.Add(time.Minute)))
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionRefResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonPollFailed))
+		})
+
+		It("should refuse to poll over SSH without known_hosts", func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "polled-ssh-key", Namespace: "default"},
+				Data:       map[string][]byte{"ssh-privatekey": []byte("key")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			})
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			project.Spec.Git.Repository = "git@example.com:analytics.git"
+			project.Spec.Git.SSHKeySecret = secret.Name
+			Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+			project = pollAt(start)
+			Expect(project.Status.ResolvedCommit).To(BeEmpty())
+			condition := meta.FindStatusCondition(project.Status.Conditions, orchestrationv1alpha1.ProjectConditionRefResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(orchestrationv1alpha1.ProjectReasonPollFailed))
+			Expect(condition.Message).To(ContainSubstring("has no known_hosts key"))
+		})
+
+		It("should poll due projects in the background", func() {
+			pollCtx, stop := context.WithCancel(ctx)
+			poller := &GitPoller{Client: k8sClient}
+			done := make(chan error)
+			go func() {
+				done <- poller.Start(pollCtx)
+			}()
+			DeferCleanup(func() {
+				stop()
+				Eventually(done).Should(Receive(BeNil()))
+			})
+
+			head := git(workTree, "rev-parse", "HEAD")
+			Eventually(func() string {
+				project := &orchestrationv1alpha1.DbtProject{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+				return project.Status.ResolvedCommit
+			}).Should(Equal(head))
+		})
+
+		It("should resolve annotated tags to the commit they point at", func() {
+			commit := git(workTree, "rev-parse", "HEAD")
+			git(workTree, "tag", "-a", "v1.0.0", "-m", "release")
+			git(workTree, "push", "-q", "origin", "v1.0.0")
+
+			project := &orchestrationv1alpha1.DbtProject{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, project)).To(Succeed())
+			Expect(lsRemote(ctx, project.Spec.Git.Repository, "v1.0.0", gitCredentials{})).To(Equal(commit))
+		})
 	})
 })
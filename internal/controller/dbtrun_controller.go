@@ -3,24 +3,41 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
 )
 
+// profilesDir is where a project's dbt profiles are mounted.
+const profilesDir = "/root/.dbt"
+
+// RunPodSelector selects the pods of runs, the only pods the controller
+// reads; the manager's cache is restricted to them.
+var RunPodSelector = labels.SelectorFromSet(labels.Set{"app.kubernetes.io/managed-by": "dagctl-dbt-operator"})
+
 type DbtRunReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	// APIReader reads ConfigMaps and Secrets uncached, so that the manager
+	// does not cache every one in the cluster; it defaults to Client.
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
 }
 
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtruns,verbs=get;list;watch;create;update;patch;delete
@@ -29,7 +46,8 @@ type DbtRunReconciler struct {
 // +kubebuilder:rbac:groups=orchestration.scalecraft.io,resources=dbtprojects,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
-// +kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
 
 func (r *DbtRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
@@ -42,6 +60,15 @@ func (r *DbtRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
+	if isRunFinished(dbtRun.Status.Phase) {
+		return ctrl.Result{}, nil
+	}
+
+	if dbtRun.Spec.Cancel {
+		log.Info("Cancelling run")
+		return r.cancelRun(ctx, &dbtRun)
+	}
+
 	var project orchestrationv1alpha1.DbtProject
 	projectKey := client.ObjectKey{
 		Namespace: dbtRun.Namespace,
@@ -60,22 +87,69 @@ func (r *DbtRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	if dbtRun.Status.JobRef == nil {
-		job, err := r.createJob(ctx, &dbtRun, &project)
+		stopping, err := replacedRunsStopping(ctx, r.Client, &dbtRun)
 		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if stopping {
+			log.Info("Waiting for replaced runs to stop", "runs", dbtRun.Annotations[replacesAnnotation])
+			return ctrl.Result{RequeueAfter: replaceRequeueInterval}, nil
+		}
+
+		if dbtRun.Spec.RerunOf != nil && dbtRun.Status.RerunOf == "" {
+			message, err := r.resolveRerun(ctx, &dbtRun, &project)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if message != "" {
+				return ctrl.Result{}, r.finishRun(ctx, &dbtRun, orchestrationv1alpha1.RunPhaseError,
+					orchestrationv1alpha1.RunReasonInvalidRerun, message)
+			}
+		}
+
+		message, err := r.missingProfiles(ctx, &project)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if message != "" {
+			return ctrl.Result{}, r.finishRun(ctx, &dbtRun, orchestrationv1alpha1.RunPhaseError,
+				orchestrationv1alpha1.RunReasonProfilesNotFound, message)
+		}
+
+		if message, err = r.missingKnownHosts(ctx, &project); err != nil {
+			return ctrl.Result{}, err
+		}
+		if message != "" {
+			return ctrl.Result{}, r.finishRun(ctx, &dbtRun, orchestrationv1alpha1.RunPhaseError,
+				orchestrationv1alpha1.RunReasonKnownHostsNotFound, message)
+		}
+
+		if err := r.ensureVolumeClaims(ctx, &dbtRun, &project); err != nil {
+			log.Error(err, "Failed to create PersistentVolumeClaims")
+			return ctrl.Result{}, err
+		}
+
+		if err := r.ensurePackageCache(ctx, &project, effectiveConfig(&dbtRun, &project).Image); err != nil {
+			log.Error(err, "Failed to create package cache PersistentVolumeClaim")
+			return ctrl.Result{}, err
+		}
+
+		// Reruns from failure already have the state of the run they resume.
+		if dbtRun.Status.StateVolumeClaim == "" {
+			if err := r.ensureStateVolume(ctx, &dbtRun, retryPolicy(&dbtRun, &project)); err != nil {
+				log.Error(err, "Failed to create state PersistentVolumeClaim")
+				return ctrl.Result{}, err
+			}
+			dbtRun.Status.StateVolumeClaim = stateVolumeClaimName(&dbtRun)
+		}
+
+		if err := r.startAttempt(ctx, &dbtRun, &project); err != nil {
 			log.Error(err, "Failed to create Job")
 			dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseError
 			r.Status().Update(ctx, &dbtRun)
 			return ctrl.Result{}, err
 		}
 
-		dbtRun.Status.JobRef = &corev1.ObjectReference{
-			Kind:       "Job",
-			APIVersion: "batch/v1",
-			Name:       job.Name,
-			Namespace:  job.Namespace,
-			UID:        job.UID,
-		}
-
 		now := metav1.Now()
 		dbtRun.Status.StartTime = &now
 		dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
@@ -92,94 +166,133 @@ func (r *DbtRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 	if err := r.Get(ctx, jobKey, &job); err != nil {
 		if apierrors.IsNotFound(err) {
-			dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseError
-			r.Status().Update(ctx, &dbtRun)
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.finishRun(ctx, &dbtRun, orchestrationv1alpha1.RunPhaseError,
+				orchestrationv1alpha1.RunReasonJobNotFound, fmt.Sprintf("Job %s no longer exists", jobKey.Name))
 		}
 		return ctrl.Result{}, err
 	}
 
 	dbtRun.Status.JobStatus = job.Status.DeepCopy()
 
+	pod, err := r.latestJobPod(ctx, &job)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pod != nil && dbtRun.Status.Commit == "" {
+		dbtRun.Status.Commit = checkedOutCommit(pod)
+	}
+
+	attempt := currentAttempt(&dbtRun)
+	var retryAfter time.Duration
 	if job.Status.Succeeded > 0 {
-		dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseSucceeded
-		now := metav1.Now()
-		dbtRun.Status.CompletionTime = &now
+		completeRun(&dbtRun, orchestrationv1alpha1.RunPhaseSucceeded, orchestrationv1alpha1.RunReasonSucceeded,
+			fmt.Sprintf("Job %s succeeded", job.Name))
+		attempt.Phase = orchestrationv1alpha1.RunPhaseSucceeded
+		attempt.CompletionTime = dbtRun.Status.CompletionTime
 
-		project.Status.LastSuccessfulTime = &now
+		project.Status.LastSuccessfulTime = dbtRun.Status.CompletionTime
 		if err := r.Status().Update(ctx, &project); err != nil {
 			log.Error(err, "Failed to update project status")
 		}
+	} else if jobTimedOut(&job) {
+		now := metav1.Now()
+		attempt.Phase = orchestrationv1alpha1.RunPhaseTimedOut
+		attempt.CompletionTime = &now
+		completeRun(&dbtRun, orchestrationv1alpha1.RunPhaseTimedOut, orchestrationv1alpha1.RunReasonTimedOut,
+			fmt.Sprintf("Job %s exceeded the timeout of %ds", job.Name, *job.Spec.ActiveDeadlineSeconds))
 	} else if job.Status.Failed > 0 {
-		dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseFailed
 		now := metav1.Now()
-		dbtRun.Status.CompletionTime = &now
+		if attempt.Phase != orchestrationv1alpha1.RunPhaseFailed {
+			attempt.Phase = orchestrationv1alpha1.RunPhaseFailed
+			attempt.FailureClass, attempt.FailedContainer, attempt.ExitCode = classifyFailure(pod)
+			attempt.CompletionTime = &now
+		}
+		if policy := retryPolicy(&dbtRun, &project); shouldRetry(policy, attempt) {
+			retryAt := metav1.NewTime(attempt.CompletionTime.Add(retryDelay(policy, attempt.Attempt)))
+			dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
+			dbtRun.Status.NextRetryTime = &retryAt
+			retryAfter = time.Until(retryAt.Time)
+		} else {
+			message := fmt.Sprintf("Job %s failed", job.Name)
+			if attempt.FailureClass != "" {
+				message += " with " + string(attempt.FailureClass)
+			}
+			completeRun(&dbtRun, orchestrationv1alpha1.RunPhaseFailed, orchestrationv1alpha1.RunReasonFailed, message)
+		}
 	} else {
 		dbtRun.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
 	}
 
+	_, _, steps, err := resolveCommands(&dbtRun, &project)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(steps) > 0 {
+		current, err := attemptSteps(&dbtRun, steps)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		dbtRun.Status.Steps = mergeStepStatuses(steps, dbtRun.Status.Steps,
+			stepStatuses(current, pod, isRunFinished(dbtRun.Status.Phase)))
+	}
+
+	if dbtRun.Status.NextRetryTime != nil && retryAfter <= 0 {
+		if err := r.startAttempt(ctx, &dbtRun, &project); err != nil {
+			log.Error(err, "Failed to create Job for retry")
+			return ctrl.Result{}, err
+		}
+		log.Info("Retrying run", "attempt", len(dbtRun.Status.Attempts))
+	}
+
 	if err := r.Status().Update(ctx, &dbtRun); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: retryAfter}, nil
 }
 
 func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) (*batchv1.Job, error) {
-	commands := run.Spec.Commands
-	if len(commands) == 0 {
-		commands = project.Spec.Commands
+	commands, _, steps, err := resolveCommands(run, project)
+	if err != nil {
+		return nil, err
 	}
-	if len(commands) == 0 {
-		commands = []string{"run"}
+	previous := resumedAttempt(run)
+	if previous != nil {
+		commands = retryArgs(commands, previous)
 	}
 
-	image := project.Spec.Image
-	if image == "" {
-		image = "ghcr.io/dbt-labs/dbt-postgres:1.7.0"
-	}
+	config := effectiveConfig(run, project)
 
-	initContainers := []corev1.Container{
-		{
-			Name:  "git-clone",
-			Image: "alpine/git:latest",
-			Command: []string{
-				"sh", "-c",
-				fmt.Sprintf("git clone %s /workspace && cd /workspace && git checkout %s",
-					project.Spec.Git.Repository,
-					getGitRef(project.Spec.Git.Ref)),
-			},
-			VolumeMounts: []corev1.VolumeMount{
-				{
-					Name:      "workspace",
-					MountPath: "/workspace",
-				},
-			},
-		},
+	source, sourceVolumes, err := sourceContainer(project, config.GitRef)
+	if err != nil {
+		return nil, err
 	}
-
-	if project.Spec.Git.SSHKeySecret != "" {
-		initContainers[0].VolumeMounts = append(initContainers[0].VolumeMounts, corev1.VolumeMount{
-			Name:      "ssh-key",
-			MountPath: "/root/.ssh",
-		})
+	initContainers := []corev1.Container{source}
+
+	python := "python"
+	requirements := pythonRequirements(project)
+	var packagesVolume *corev1.Volume
+	if len(requirements) > 0 {
+		install, volume := packagesContainer(project, config, requirements)
+		initContainers = append(initContainers, install)
+		packagesVolume = &volume
+		python = packagesPython
 	}
 
-	dbtCmd := []string{"dbt"}
-	dbtCmd = append(dbtCmd, commands...)
-
-	workDir := "/workspace"
-	if project.Spec.Git.Path != "" && project.Spec.Git.Path != "/" {
-		workDir = fmt.Sprintf("/workspace/%s", project.Spec.Git.Path)
+	workDir := workspaceDir
+	if path := projectPath(project); path != "" && path != "/" {
+		workDir = fmt.Sprintf("%s/%s", workspaceDir, path)
 	}
 
 	container := corev1.Container{
 		Name:       "dbt",
-		Image:      image,
-		Command:    dbtCmd,
+		Image:      config.Image,
+		Command:    dbtCommand(python, false),
+		Args:       commands,
 		WorkingDir: workDir,
-		Env:        project.Spec.Env,
-		Resources:  project.Spec.Resources,
+		Env:        config.Env,
+		EnvFrom:    config.EnvFrom,
+		Resources:  config.Resources,
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:      "workspace",
@@ -188,15 +301,51 @@ func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1al
 		},
 	}
 
-	if project.Spec.ProfilesConfigMap != "" {
+	if profiles := profilesVolumeSource(project); profiles != nil {
 		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
 			Name:      "profiles",
-			MountPath: "/root/.dbt",
+			MountPath: profilesDir,
+			ReadOnly:  true,
+		})
+		container.Env = mergeEnv([]corev1.EnvVar{{Name: "DBT_PROFILES_DIR", Value: profilesDir}}, container.Env)
+	}
+
+	if run.Status.StateVolumeClaim != "" {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      stateVolumeName,
+			MountPath: stateMountPath,
+		})
+		container.Env = mergeEnv([]corev1.EnvVar{{Name: "DBT_TARGET_PATH", Value: stateMountPath + "/target"}}, container.Env)
+	}
+
+	if packagesVolume != nil {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      packagesVolumeName,
+			MountPath: packagesMountPath,
+			ReadOnly:  true,
 		})
 	}
 
 	container.VolumeMounts = append(container.VolumeMounts, project.Spec.VolumeMounts...)
 
+	containers := []corev1.Container{container}
+	dbtCommand := fmt.Sprintf("dbt %v", commands)
+	if len(steps) > 0 {
+		if previous != nil {
+			if steps, err = retrySteps(steps, previous); err != nil {
+				return nil, err
+			}
+		}
+		stepContainers, stepCommands, err := stepContainers(steps, container, python)
+		if err != nil {
+			return nil, err
+		}
+		last := len(stepContainers) - 1
+		initContainers = append(initContainers, stepContainers[:last]...)
+		containers = stepContainers[last:]
+		dbtCommand = strings.Join(stepCommands, " && ")
+	}
+
 	volumes := []corev1.Volume{
 		{
 			Name: "workspace",
@@ -206,31 +355,31 @@ func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1al
 		},
 	}
 
-	if project.Spec.ProfilesConfigMap != "" {
+	volumes = append(volumes, volumeClaimVolumes(run, project)...)
+
+	if run.Status.StateVolumeClaim != "" {
 		volumes = append(volumes, corev1.Volume{
-			Name: "profiles",
+			Name: stateVolumeName,
 			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: project.Spec.ProfilesConfigMap,
-					},
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: run.Status.StateVolumeClaim,
 				},
 			},
 		})
 	}
 
-	if project.Spec.Git.SSHKeySecret != "" {
+	if profiles := profilesVolumeSource(project); profiles != nil {
 		volumes = append(volumes, corev1.Volume{
-			Name: "ssh-key",
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName:  project.Spec.Git.SSHKeySecret,
-					DefaultMode: ptr.To(int32(0400)),
-				},
-			},
+			Name:         "profiles",
+			VolumeSource: *profiles,
 		})
 	}
 
+	volumes = append(volumes, sourceVolumes...)
+	if packagesVolume != nil {
+		volumes = append(volumes, *packagesVolume)
+	}
+
 	// Create labels with run metadata
 	labels := map[string]string{
 		"app.kubernetes.io/name":               "dagctl-dbt",
@@ -240,22 +389,33 @@ func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1al
 		"orchestration.scalecraft.io/run":      run.Name,
 		"orchestration.scalecraft.io/run-type": string(run.Spec.Type),
 	}
+	if name := run.Labels[scheduleLabel]; name != "" {
+		labels[scheduleLabel] = name
+	}
 
 	// Add command as annotation (labels have character limits)
 	annotations := map[string]string{
-		"orchestration.scalecraft.io/dbt-command": fmt.Sprintf("dbt %v", commands),
+		"orchestration.scalecraft.io/dbt-command": dbtCommand,
 		"orchestration.scalecraft.io/created-at":  metav1.Now().Format("2006-01-02T15:04:05Z"),
 	}
 
+	// The run's retry policy replaces the Job's own retries.
+	var backoffLimit *int32
+	if retryPolicy(run, project) != nil {
+		backoffLimit = ptr.To(int32(0))
+	}
+
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        fmt.Sprintf("%s-%s", run.Name, "job"),
+			Name:        jobName(run, len(run.Status.Attempts)),
 			Namespace:   run.Namespace,
 			Labels:      labels,
 			Annotations: annotations,
 		},
 		Spec: batchv1.JobSpec{
 			TTLSecondsAfterFinished: run.Spec.TTLSecondsAfterFinished,
+			BackoffLimit:            backoffLimit,
+			ActiveDeadlineSeconds:   runTimeout(run, project),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      labels,
@@ -263,15 +423,21 @@ func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1al
 				},
 				Spec: corev1.PodSpec{
 					InitContainers:     initContainers,
-					Containers:         []corev1.Container{container},
+					Containers:         containers,
 					Volumes:            volumes,
 					RestartPolicy:      corev1.RestartPolicyNever,
 					ServiceAccountName: project.Spec.ServiceAccountName,
+
+					TerminationGracePeriodSeconds: terminationGracePeriod(project),
 				},
 			},
 		},
 	}
 
+	if err := applyPodTemplates(&job.Spec.Template, project, run); err != nil {
+		return nil, err
+	}
+
 	if err := controllerutil.SetControllerReference(run, job, r.Scheme); err != nil {
 		return nil, err
 	}
@@ -283,6 +449,268 @@ func (r *DbtRunReconciler) createJob(ctx context.Context, run *orchestrationv1al
 	return job, nil
 }
 
+// effectiveConfig merges the run over its schedule and the project.
+func effectiveConfig(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) orchestrationv1alpha1.EffectiveRunConfig {
+	config := orchestrationv1alpha1.EffectiveRunConfig{
+		Image:     project.Spec.Image,
+		Env:       project.Spec.Env,
+		Resources: project.Spec.Resources,
+	}
+	if schedule := findSchedule(project, run.Labels[scheduleLabel]); schedule != nil {
+		if schedule.Image != "" {
+			config.Image = schedule.Image
+		}
+		config.Env = mergeEnv(config.Env, schedule.Env)
+		if schedule.Resources != nil {
+			config.Resources = *schedule.Resources
+		}
+	}
+
+	if run.Spec.Image != "" {
+		config.Image = run.Spec.Image
+	}
+	config.Env = mergeEnv(config.Env, run.Spec.Env)
+	config.EnvFrom = run.Spec.EnvFrom
+	if run.Spec.Resources != nil {
+		config.Resources = *run.Spec.Resources
+	}
+	if config.Image == "" && project.Spec.Adapter != nil {
+		config.Image = defaultPackagesImage
+	}
+	if config.Image == "" {
+		config.Image = "ghcr.io/dbt-labs/dbt-postgres:1.7.0"
+	}
+
+	// Only git sources have refs.
+	git := projectGit(project)
+	if git == nil {
+		return config
+	}
+	config.GitRef = getGitRef(git.Ref)
+	// Runs started for a specific commit build exactly that commit, unless
+	// they ask for another ref.
+	if commit := run.Annotations[commitAnnotation]; commit != "" {
+		config.GitRef = commit
+	}
+	if run.Spec.GitRef != "" {
+		config.GitRef = run.Spec.GitRef
+	}
+	// Retries build the commit of the first attempt.
+	if run.Status.Commit != "" {
+		config.GitRef = run.Status.Commit
+	}
+	return config
+}
+
+// latestJobPod returns the most recently created pod of the job, or nil.
+func (r *DbtRunReconciler) latestJobPod(ctx context.Context, job *batchv1.Job) (*corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return nil, err
+	}
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		if latest == nil || latest.CreationTimestamp.Before(&pods.Items[i].CreationTimestamp) {
+			latest = &pods.Items[i]
+		}
+	}
+	return latest, nil
+}
+
+// profilesVolumeSource returns the volume holding the project's dbt profiles,
+// or nil when it has none.
+func profilesVolumeSource(project *orchestrationv1alpha1.DbtProject) *corev1.VolumeSource {
+	var sources []corev1.VolumeProjection
+	if name := project.Spec.ProfilesConfigMap; name != "" {
+		sources = append(sources, corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+		})
+	}
+	if name := project.Spec.ProfilesSecret; name != "" {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+		})
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	return &corev1.VolumeSource{
+		Projected: &corev1.ProjectedVolumeSource{
+			Sources:     sources,
+			DefaultMode: ptr.To(int32(0444)),
+		},
+	}
+}
+
+// missingProfiles checks that the project's profiles ConfigMap and Secret
+// exist, so that a run fails with a clear reason instead of leaving a pod
+// stuck in ContainerCreating. It returns a message naming what is missing.
+func (r *DbtRunReconciler) missingProfiles(ctx context.Context, project *orchestrationv1alpha1.DbtProject) (string, error) {
+	objects := map[string]client.Object{}
+	if name := project.Spec.ProfilesConfigMap; name != "" {
+		objects[fmt.Sprintf("ConfigMap %q", name)] = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	if name := project.Spec.ProfilesSecret; name != "" {
+		objects[fmt.Sprintf("Secret %q", name)] = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	var missing []string
+	for description, obj := range objects {
+		err := apiReader(r.APIReader, r.Client).Get(ctx, client.ObjectKey{Namespace: project.Namespace, Name: obj.GetName()}, obj)
+		if apierrors.IsNotFound(err) {
+			missing = append(missing, description)
+		} else if err != nil {
+			return "", err
+		}
+	}
+	if len(missing) == 0 {
+		return "", nil
+	}
+	slices.Sort(missing)
+	return fmt.Sprintf("dbt profiles %s not found", strings.Join(missing, " and ")), nil
+}
+
+// missingKnownHosts checks that the SSH key Secret of the project's git
+// repository lists the server's host keys, without which the checkout would
+// fail. It returns a message saying what is missing. A missing Secret is left
+// to the pod, which waits for it.
+func (r *DbtRunReconciler) missingKnownHosts(ctx context.Context, project *orchestrationv1alpha1.DbtProject) (string, error) {
+	git := projectGit(project)
+	if git == nil || git.SSHKeySecret == "" {
+		return "", nil
+	}
+	var secret corev1.Secret
+	err := apiReader(r.APIReader, r.Client).Get(ctx, client.ObjectKey{Namespace: project.Namespace, Name: git.SSHKeySecret}, &secret)
+	if err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	if len(secret.Data[gitKnownHostsKey]) == 0 {
+		return fmt.Sprintf("SSH key Secret %q has no %s key, so the host key of the git server cannot be checked", secret.Name, gitKnownHostsKey), nil
+	}
+	return "", nil
+}
+
+// apiReader returns reader, or c when it is not set.
+func apiReader(reader client.Reader, c client.Client) client.Reader {
+	if reader == nil {
+		return c
+	}
+	return reader
+}
+
+// finishRun moves a run to a final phase and records why in its Complete
+// condition.
+func (r *DbtRunReconciler) finishRun(ctx context.Context, run *orchestrationv1alpha1.DbtRun, phase orchestrationv1alpha1.RunPhase, reason, message string) error {
+	completeRun(run, phase, reason, message)
+	return r.Status().Update(ctx, run)
+}
+
+// cancelReasonAnnotation records why a run was cancelled by the controller
+// rather than by a user, as a reason of its Complete condition.
+const cancelReasonAnnotation = "orchestration.scalecraft.io/cancel-reason"
+
+// cancelRequeueInterval is how often a Cancelling run checks whether its pod
+// has stopped, besides the pod's own events.
+const cancelRequeueInterval = 5 * time.Second
+
+// cancelRun stops a run on request of spec.cancel. Deleting its Job stops the
+// pod gracefully. The run stays Cancelling until the pod has stopped, so that
+// a run replacing it does not start while dbt is still cancelling its
+// queries, and is then kept as a record that completed when the pod stopped.
+func (r *DbtRunReconciler) cancelRun(ctx context.Context, run *orchestrationv1alpha1.DbtRun) (ctrl.Result, error) {
+	now := metav1.Now()
+	completionTime := &now
+	if run.Status.JobRef != nil {
+		if attempt := currentAttempt(run); attempt.Phase == orchestrationv1alpha1.RunPhaseRunning {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      run.Status.JobRef.Name,
+					Namespace: run.Status.JobRef.Namespace,
+				},
+			}
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+			var pods corev1.PodList
+			if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+				return ctrl.Result{}, err
+			}
+			if slices.ContainsFunc(pods.Items, podStopping) {
+				if run.Status.Phase != orchestrationv1alpha1.RunPhaseCancelling {
+					run.Status.Phase = orchestrationv1alpha1.RunPhaseCancelling
+					run.Status.NextRetryTime = nil
+					if err := r.Status().Update(ctx, run); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				return ctrl.Result{RequeueAfter: cancelRequeueInterval}, nil
+			}
+			if stoppedAt := podsStoppedAt(pods.Items); stoppedAt != nil {
+				completionTime = stoppedAt
+			}
+			attempt.Phase = orchestrationv1alpha1.RunPhaseCancelled
+			attempt.CompletionTime = completionTime
+		}
+	}
+	run.Status.NextRetryTime = nil
+	reason, message := orchestrationv1alpha1.RunReasonCancelled, "Cancelled through spec.cancel"
+	if run.Annotations[cancelReasonAnnotation] == orchestrationv1alpha1.RunReasonReplaced {
+		reason, message = orchestrationv1alpha1.RunReasonReplaced, "Cancelled by a newer run under the Replace concurrency policy"
+	}
+	completeRun(run, orchestrationv1alpha1.RunPhaseCancelled, reason, message)
+	run.Status.CompletionTime = completionTime
+	return ctrl.Result{}, r.Status().Update(ctx, run)
+}
+
+// podStopping reports whether a pod of a deleted Job has yet to stop. Pods in
+// their termination grace period have not, as dbt may still be cancelling its
+// queries in them.
+func podStopping(pod corev1.Pod) bool {
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
+// podsStoppedAt returns when the last container of pods stopped, or nil when
+// none reports it, e.g. because the pods are already gone.
+func podsStoppedAt(pods []corev1.Pod) *metav1.Time {
+	var stoppedAt *metav1.Time
+	for _, pod := range pods {
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			if terminated := status.State.Terminated; terminated != nil && !terminated.FinishedAt.IsZero() &&
+				(stoppedAt == nil || stoppedAt.Before(&terminated.FinishedAt)) {
+				stoppedAt = terminated.FinishedAt.DeepCopy()
+			}
+		}
+	}
+	return stoppedAt
+}
+
+// completeRun is finishRun without updating the run.
+func completeRun(run *orchestrationv1alpha1.DbtRun, phase orchestrationv1alpha1.RunPhase, reason, message string) {
+	now := metav1.Now()
+	run.Status.Phase = phase
+	run.Status.CompletionTime = &now
+	meta.SetStatusCondition(&run.Status.Conditions, metav1.Condition{
+		Type:    orchestrationv1alpha1.RunConditionComplete,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// isRunFinished reports whether a run has reached a final phase and no
+// longer needs its Job watched.
+func isRunFinished(phase orchestrationv1alpha1.RunPhase) bool {
+	switch phase {
+	case orchestrationv1alpha1.RunPhaseSucceeded,
+		orchestrationv1alpha1.RunPhaseFailed,
+		orchestrationv1alpha1.RunPhaseError,
+		orchestrationv1alpha1.RunPhaseTimedOut,
+		orchestrationv1alpha1.RunPhaseCancelled:
+		return true
+	}
+	return false
+}
+
 func getGitRef(ref string) string {
 	if ref == "" {
 		return "main"
@@ -290,9 +718,36 @@ func getGitRef(ref string) string {
 	return ref
 }
 
+// mergeEnv returns base with the variables in overrides added, replacing
+// those of the same name.
+func mergeEnv(base, overrides []corev1.EnvVar) []corev1.EnvVar {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make([]corev1.EnvVar, 0, len(base)+len(overrides))
+	for _, env := range base {
+		if !slices.ContainsFunc(overrides, func(o corev1.EnvVar) bool { return o.Name == env.Name }) {
+			merged = append(merged, env)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+// runForPod maps a pod of a run's Job to the run, so that the progress of
+// its containers is picked up as it happens.
+func runForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()["orchestration.scalecraft.io/run"]
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
 func (r *DbtRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&orchestrationv1alpha1.DbtRun{}).
 		Owns(&batchv1.Job{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(runForPod)).
 		Complete(r)
 }
@@ -17,12 +17,35 @@ limitations under the License.
 package controller
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,4 +104,1975 @@ var _ = Describe("DbtRun Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When the project has an AuthSecret", func() {
+		ctx := context.Background()
+
+		It("mounts the secret for a credential helper instead of inlining it", func() {
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "https-auth-project", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{
+						Repository: "https://example.com/private.git",
+						AuthSecret: "git-auth",
+					},
+				},
+			}
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "https-auth-run", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			job, err := reconciler.createJob(ctx, run, project)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+			})
+
+			clone := job.Spec.Template.Spec.InitContainers[0]
+			Expect(clone.Env).To(ContainElement(corev1.EnvVar{Name: "GIT_CONFIG_KEY_0", Value: "credential.https://example.com.helper"}))
+			Expect(clone.VolumeMounts).To(ContainElement(HaveField("MountPath", gitAuthMountPath)))
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("VolumeSource.Secret.SecretName", "git-auth")))
+		})
+
+		It("answers credential requests from the mounted secret", func() {
+			dir := GinkgoT().TempDir()
+			config := "credential." + gitCredentialScope("https://example.com/private.git") + ".helper=" + gitCredentialHelper(dir)
+			fill := func() string {
+				cmd := exec.Command("git", "-c", config, "credential", "fill")
+				cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+				cmd.Stdin = strings.NewReader("protocol=https\nhost=example.com\n\n")
+				out, err := cmd.Output()
+				Expect(err).NotTo(HaveOccurred())
+				return string(out)
+			}
+
+			Expect(os.WriteFile(filepath.Join(dir, gitTokenKey), []byte("t0ken"), 0o600)).To(Succeed())
+			Expect(fill()).To(And(ContainSubstring("username=x-access-token\n"), ContainSubstring("password=t0ken\n")))
+
+			Expect(os.WriteFile(filepath.Join(dir, gitUsernameKey), []byte("alice"), 0o600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, gitPasswordKey), []byte("pa55"), 0o600)).To(Succeed())
+			Expect(fill()).To(And(ContainSubstring("username=alice\n"), ContainSubstring("password=pa55\n")))
+
+			By("not answering for other hosts")
+			cmd := exec.Command("git", "-c", config, "credential", "fill")
+			cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+			cmd.Stdin = strings.NewReader("protocol=https\nhost=submodules.example.org\n\n")
+			out, _ := cmd.Output()
+			Expect(string(out)).NotTo(ContainSubstring("pa55"))
+		})
+
+		It("scopes the credentials of the operator to the repository's host", func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "git-auth"},
+				Data:       map[string][]byte{gitTokenKey: []byte("t0ken")},
+			}
+			env, cleanup, err := gitEnv("https://git@example.com:8443/private.git", gitCredentials{auth: secret})
+			Expect(err).NotTo(HaveOccurred())
+			defer cleanup()
+			Expect(env).To(ContainElement("GIT_CONFIG_KEY_0=http.https://example.com:8443.extraHeader"))
+			Expect(env).NotTo(ContainElement("GIT_CONFIG_KEY_0=http.extraHeader"))
+		})
+	})
+
+	Context("When the project has profiles", func() {
+		ctx := context.Background()
+		var project *orchestrationv1alpha1.DbtProject
+
+		BeforeEach(func() {
+			project = &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "profiles-project", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git:               orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+					ProfilesConfigMap: "dbt-profiles",
+					ProfilesSecret:    "dbt-credentials",
+				},
+			}
+			Expect(k8sClient.Create(ctx, project)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			})
+		})
+
+		createRun := func(name string) *orchestrationv1alpha1.DbtRun {
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+			return run
+		}
+
+		It("fails the run when the profiles Secret is missing", func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "dbt-profiles", Namespace: "default"},
+				Data:       map[string]string{"profiles.yml": "default: {}"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			})
+
+			run := createRun("profiles-missing-run")
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: run.Name, Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: run.Name, Namespace: "default"}, run)).To(Succeed())
+			Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseError))
+			Expect(run.Status.JobRef).To(BeNil())
+			Expect(run.Status.Conditions).To(ContainElement(And(
+				HaveField("Reason", orchestrationv1alpha1.RunReasonProfilesNotFound),
+				HaveField("Message", ContainSubstring(`Secret "dbt-credentials"`)),
+			)))
+		})
+
+		It("mounts the ConfigMap and Secret together as the profiles directory", func() {
+			run := createRun("profiles-run")
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			job, err := reconciler.createJob(ctx, run, project)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+			})
+
+			dbt := job.Spec.Template.Spec.Containers[0]
+			Expect(dbt.Env).To(ContainElement(corev1.EnvVar{Name: "DBT_PROFILES_DIR", Value: profilesDir}))
+			Expect(dbt.VolumeMounts).To(ContainElement(HaveField("MountPath", profilesDir)))
+
+			var sources []corev1.VolumeProjection
+			for _, volume := range job.Spec.Template.Spec.Volumes {
+				if volume.Name == "profiles" {
+					sources = volume.Projected.Sources
+				}
+			}
+			Expect(sources).To(HaveLen(2))
+			Expect(sources[0].ConfigMap.Name).To(Equal("dbt-profiles"))
+			Expect(sources[1].Secret.Name).To(Equal("dbt-credentials"))
+		})
+	})
+
+	Context("When the project has volume claim templates", func() {
+		ctx := context.Background()
+
+		newProject := func(name string, scope orchestrationv1alpha1.VolumeClaimScope) *orchestrationv1alpha1.DbtProject {
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+						ObjectMeta: metav1.ObjectMeta{Name: "target"},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+							Resources: corev1.VolumeResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+							},
+						},
+					}},
+					VolumeClaimScope: scope,
+					VolumeMounts:     []corev1.VolumeMount{{Name: "target", MountPath: "/workspace/target"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, project)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			})
+			return project
+		}
+
+		runJob := func(project *orchestrationv1alpha1.DbtProject, name string) (*orchestrationv1alpha1.DbtRun, *batchv1.Job) {
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			var job batchv1.Job
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name + "-job", Namespace: "default"}, &job)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+			})
+			return run, &job
+		}
+
+		expectClaim := func(job *batchv1.Job, claimName string, owner metav1.Object) {
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(
+				HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", claimName)))
+
+			var pvc corev1.PersistentVolumeClaim
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: claimName, Namespace: "default"}, &pvc)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &pvc))).To(Succeed())
+			})
+			Expect(metav1.IsControlledBy(&pvc, owner)).To(BeTrue())
+		}
+
+		It("shares one claim per template between the runs of a project", func() {
+			project := newProject("pvc-project", "")
+			_, first := runJob(project, "pvc-run-1")
+			_, second := runJob(project, "pvc-run-2")
+
+			expectClaim(first, "target-pvc-project", project)
+			expectClaim(second, "target-pvc-project", project)
+			Expect(first.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(
+				corev1.VolumeMount{Name: "target", MountPath: "/workspace/target"}))
+		})
+
+		It("creates claims for every run with the Run scope", func() {
+			project := newProject("pvc-run-project", orchestrationv1alpha1.VolumeClaimScopeRun)
+			run, job := runJob(project, "pvc-scoped-run")
+
+			expectClaim(job, "target-pvc-scoped-run", run)
+		})
+
+		It("rejects templates named after the controller's volumes", func() {
+			for _, name := range []string{"workspace", "profiles", "source", "ssh-key", "git-auth", "oci-auth", stateVolumeName, packagesVolumeName} {
+				project := &orchestrationv1alpha1.DbtProject{
+					ObjectMeta: metav1.ObjectMeta{Name: "pvc-reserved-project", Namespace: "default"},
+					Spec: orchestrationv1alpha1.DbtProjectSpec{
+						Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+						VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+							ObjectMeta: metav1.ObjectMeta{Name: name},
+						}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, project)).To(MatchError(ContainSubstring("reserved volume names")), name)
+			}
+		})
+	})
+
+	Context("When checking out the repository", func() {
+		ctx := context.Background()
+		var remote, workTree string
+
+		git := func(dir string, args ...string) string {
+			cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+
+		BeforeEach(func() {
+			dir := GinkgoT().TempDir()
+			remote = filepath.Join(dir, "analytics.git")
+			workTree = filepath.Join(dir, "work")
+			git(dir, "init", "-q", "--bare", remote)
+			git(remote, "config", "uploadpack.allowFilter", "true")
+			git(dir, "init", "-q", workTree)
+			Expect(os.MkdirAll(filepath.Join(workTree, "analytics", "models"), 0o755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workTree, "other"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workTree, "analytics", "dbt_project.yml"), []byte("name: analytics\n"), 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workTree, "other", "README"), []byte("other\n"), 0o644)).To(Succeed())
+			git(workTree, "add", "-A")
+			git(workTree, "commit", "-q", "-m", "initial")
+			git(workTree, "tag", "-a", "v1.0.0", "-m", "release")
+			git(workTree, "commit", "-q", "--allow-empty", "-m", "second")
+			git(workTree, "push", "-q", "file://"+remote, "HEAD:refs/heads/main", "v1.0.0")
+		})
+
+		checkout := func(config orchestrationv1alpha1.GitConfig, ref string, env ...string) (string, string, error) {
+			dir := GinkgoT().TempDir()
+			commitFile := filepath.Join(GinkgoT().TempDir(), "commit")
+
+			cmd := exec.Command("sh", "-c", gitCheckoutScript)
+			cmd.Env = append(os.Environ(), env...)
+			for _, env := range gitCheckoutEnv(&config, ref, dir) {
+				if env.Name == "CHECKOUT_COMMIT_FILE" {
+					env.Value = commitFile
+				}
+				cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+			}
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return "", dir, fmt.Errorf("%w: %s", err, out)
+			}
+			commit, err := os.ReadFile(commitFile)
+			return string(commit), dir, err
+		}
+
+		It("checks out branches, annotated tags and commits", func() {
+			config := orchestrationv1alpha1.GitConfig{Repository: "file://" + remote}
+
+			commit, _, err := checkout(config, "main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commit).To(Equal(git(workTree, "rev-parse", "HEAD")))
+
+			commit, _, err = checkout(config, "v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commit).To(Equal(git(workTree, "rev-parse", "v1.0.0^{commit}")))
+
+			commit, _, err = checkout(config, git(workTree, "rev-parse", "HEAD~1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commit).To(Equal(git(workTree, "rev-parse", "HEAD~1")))
+		})
+
+		It("finds branches among all fetched refs when fetching by name fails", func() {
+			realGit, err := exec.LookPath("git")
+			Expect(err).NotTo(HaveOccurred())
+			bin := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\n"+
+				"if [ \"$1\" = fetch ]; then for arg; do [ \"$arg\" = -- ] && exit 1; done; fi\n"+
+				"exec "+realGit+" \"$@\"\n"), 0o755)).To(Succeed())
+			git(workTree, "push", "-q", "file://"+remote, "HEAD~1:refs/heads/release")
+			config := orchestrationv1alpha1.GitConfig{Repository: "file://" + remote}
+
+			for _, ref := range []string{"release", "refs/heads/release"} {
+				commit, _, err := checkout(config, ref, "PATH="+bin+":"+os.Getenv("PATH"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(commit).To(Equal(git(workTree, "rev-parse", "HEAD~1")))
+			}
+
+			commit, _, err := checkout(config, "v1.0.0", "PATH="+bin+":"+os.Getenv("PATH"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commit).To(Equal(git(workTree, "rev-parse", "v1.0.0^{commit}")))
+		})
+
+		It("only checks out the project path of a sparse checkout", func() {
+			config := orchestrationv1alpha1.GitConfig{Repository: "file://" + remote, Path: "analytics", Sparse: true}
+
+			_, dir, err := checkout(config, "main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Join(dir, "analytics", "dbt_project.yml")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "other")).NotTo(BeADirectory())
+		})
+
+		It("does not interpret refs with the shell", func() {
+			dir := GinkgoT().TempDir()
+			config := orchestrationv1alpha1.GitConfig{Repository: "file://" + remote}
+
+			_, _, err := checkout(config, "main; touch "+filepath.Join(dir, "pwned"))
+			Expect(err).To(HaveOccurred())
+			Expect(filepath.Join(dir, "pwned")).NotTo(BeAnExistingFile())
+		})
+
+		It("checks host keys strictly and fails without known_hosts", func() {
+			bin, sshDir := GinkgoT().TempDir(), GinkgoT().TempDir()
+			calls := filepath.Join(bin, "calls")
+			Expect(os.WriteFile(filepath.Join(bin, "ssh"),
+				[]byte("#!/bin/sh\necho \"$(stat -c %a \"$2\") $*\" >> "+calls+"\nexit 1\n"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sshDir, "ssh-privatekey"), []byte("key"), 0o444)).To(Succeed())
+
+			fetch := func() string {
+				config := orchestrationv1alpha1.GitConfig{Repository: "ssh://git@example.com/analytics.git", SSHKeySecret: "git-ssh"}
+				cmd := exec.Command("sh", "-c", gitCheckoutScript)
+				cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+				for _, env := range gitCheckoutEnv(&config, "main", GinkgoT().TempDir()) {
+					if env.Name == "CHECKOUT_SSH_DIR" {
+						env.Value = sshDir
+					}
+					cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+				}
+				out, err := cmd.CombinedOutput()
+				Expect(err).To(HaveOccurred())
+				return string(out)
+			}
+
+			Expect(fetch()).To(ContainSubstring("the SSH key secret has no known_hosts"))
+			Expect(calls).NotTo(BeAnExistingFile())
+
+			Expect(os.WriteFile(filepath.Join(sshDir, "known_hosts"), []byte("example.com ssh-ed25519 AAAA\n"), 0o444)).To(Succeed())
+			Expect(fetch()).NotTo(ContainSubstring("known_hosts"))
+			Expect(os.ReadFile(calls)).To(And(
+				HavePrefix("600 -i "),
+				ContainSubstring("-o UserKnownHostsFile="+filepath.Join(sshDir, "known_hosts")),
+				ContainSubstring("-o StrictHostKeyChecking=yes"),
+			))
+		})
+
+		It("fails runs whose SSH key secret has no known_hosts", func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "unpinned-ssh-key", Namespace: "default"},
+				Data:       map[string][]byte{"ssh-privatekey": []byte("key")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			})
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "unpinned-project", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{Repository: "git@example.com:analytics.git", SSHKeySecret: secret.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, project)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			})
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "unpinned-run", Namespace: "default"},
+				Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+			Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseError))
+			Expect(run.Status.JobRef).To(BeNil())
+			Expect(run.Status.Conditions).To(ContainElement(And(
+				HaveField("Reason", orchestrationv1alpha1.RunReasonKnownHostsNotFound),
+				HaveField("Message", ContainSubstring(`"unpinned-ssh-key" has no known_hosts`)),
+			)))
+		})
+
+		It("records the checked out commit in the run status", func() {
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "checkout-project", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, project)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			})
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "checkout-run", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: run.Name + "-job", Namespace: "default"}})).To(Succeed())
+			})
+
+			commit := git(workTree, "rev-parse", "HEAD")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      run.Name + "-job-abcde",
+					Namespace: "default",
+					Labels:    map[string]string{"job-name": run.Name + "-job"},
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "dbt", Image: "dbt"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			})
+			pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+				Name: gitCloneContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: commit,
+				}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+			Expect(run.Status.Commit).To(Equal(commit))
+		})
+	})
+
+	Context("When the run has steps", func() {
+		ctx := context.Background()
+
+		steps := []orchestrationv1alpha1.DbtStep{
+			{Name: "deps", Commands: []string{"deps"}},
+			{Name: "build", Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build", Select: []string{"tag:daily"}}, ContinueOnFailure: true},
+			{Name: "docs", Commands: []string{"docs", "generate"}},
+		}
+
+		It("runs every step but the last as an init container", func() {
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "steps-project", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtProjectSpec{
+					Git:   orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+					Steps: steps,
+				},
+			}
+			run := &orchestrationv1alpha1.DbtRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "steps-run", Namespace: "default"},
+				Spec: orchestrationv1alpha1.DbtRunSpec{
+					ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, run)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+			})
+
+			reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			job, err := reconciler.createJob(ctx, run, project)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+			})
+
+			podSpec := job.Spec.Template.Spec
+			Expect(podSpec.InitContainers).To(HaveLen(3))
+			Expect(podSpec.InitContainers[0].Name).To(Equal(gitCloneContainerName))
+			Expect(podSpec.InitContainers[1].Name).To(Equal("dbt-deps"))
+			Expect(podSpec.InitContainers[1].Command).To(Equal(dbtCommand("python", false)))
+			Expect(podSpec.InitContainers[1].Args).To(Equal([]string{"deps"}))
+			Expect(podSpec.InitContainers[2].Name).To(Equal("dbt-build"))
+			Expect(podSpec.InitContainers[2].Command).To(Equal(dbtCommand("python", true)))
+			Expect(podSpec.InitContainers[2].Args).To(Equal([]string{"build", "--select", "tag:daily"}))
+			Expect(podSpec.Containers).To(HaveLen(1))
+			Expect(podSpec.Containers[0].Name).To(Equal("dbt-docs"))
+			Expect(podSpec.Containers[0].WorkingDir).To(Equal("/workspace"))
+		})
+
+		It("reports each step's phase and exit code", func() {
+			started := metav1.NewTime(time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC))
+			finished := metav1.NewTime(started.Add(time.Minute))
+			terminated := func(name string, exitCode int32, message string) corev1.ContainerStatus {
+				return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: exitCode, Message: message, StartedAt: started, FinishedAt: finished,
+				}}}
+			}
+
+			pod := &corev1.Pod{Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					terminated(gitCloneContainerName, 0, ""),
+					terminated("dbt-deps", 0, ""),
+					terminated("dbt-build", 0, "1"),
+				},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "dbt-docs",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: finished}},
+				}},
+			}}
+			statuses := stepStatuses(steps, pod, false)
+			Expect(statuses).To(HaveLen(3))
+			Expect(statuses[0].Phase).To(Equal(orchestrationv1alpha1.StepPhaseSucceeded))
+			Expect(statuses[0].CompletionTime).To(Equal(&finished))
+			Expect(statuses[1].Phase).To(Equal(orchestrationv1alpha1.StepPhaseFailed))
+			Expect(statuses[1].ExitCode).To(Equal(ptr.To(int32(1))))
+			Expect(statuses[2].Phase).To(Equal(orchestrationv1alpha1.StepPhaseRunning))
+
+			pod.Status.InitContainerStatuses[1] = terminated("dbt-deps", 2, "")
+			pod.Status.InitContainerStatuses = pod.Status.InitContainerStatuses[:2]
+			pod.Status.ContainerStatuses = nil
+			statuses = stepStatuses(steps, pod, true)
+			Expect(statuses[0].Phase).To(Equal(orchestrationv1alpha1.StepPhaseFailed))
+			Expect(statuses[1].Phase).To(Equal(orchestrationv1alpha1.StepPhaseSkipped))
+			Expect(statuses[2].Phase).To(Equal(orchestrationv1alpha1.StepPhaseSkipped))
+		})
+	})
+})
+
+var _ = Describe("DbtRun retries", func() {
+	ctx := context.Background()
+
+	failPod := func(job *batchv1.Job, container string, exitCode int32) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job.Name + "-abcde",
+				Namespace: "default",
+				Labels:    map[string]string{"job-name": job.Name},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "dbt", Image: "dbt"}}},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+		})
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  container,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+		}}
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+		job.Status.Failed = 1
+		Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	It("retries failed nodes with dbt retry until the policy is exhausted", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "retry-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:      orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Commands: []string{"build"},
+				RetryPolicy: &orchestrationv1alpha1.RetryPolicy{
+					MaxAttempts: 2,
+					Backoff:     &metav1.Duration{},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "retry-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var pvc corev1.PersistentVolumeClaim
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "retry-run-dbt-state", Namespace: "default"}, &pvc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &pvc)).To(Succeed())
+		})
+
+		var first batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "retry-run-job", Namespace: "default"}, &first)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &first)).To(Succeed())
+		})
+		Expect(first.Spec.BackoffLimit).To(Equal(ptr.To(int32(0))))
+		container := first.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(Equal([]string{"build"}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "DBT_TARGET_PATH", Value: "/dbt-state/target"}))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "dbt-state", MountPath: "/dbt-state"}))
+
+		failPod(&first, "dbt", 1)
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseRunning))
+		Expect(run.Status.JobRef.Name).To(Equal("retry-run-job-2"))
+		Expect(run.Status.Attempts).To(HaveLen(2))
+		Expect(run.Status.Attempts[0].Phase).To(Equal(orchestrationv1alpha1.RunPhaseFailed))
+		Expect(run.Status.Attempts[0].FailureClass).To(Equal(orchestrationv1alpha1.FailureClassNode))
+		Expect(run.Status.Attempts[0].ExitCode).To(Equal(ptr.To(int32(1))))
+
+		var second batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "retry-run-job-2", Namespace: "default"}, &second)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &second)).To(Succeed())
+		})
+		Expect(second.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"retry"}))
+
+		failPod(&second, "dbt", 1)
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseFailed))
+		Expect(run.Status.Attempts).To(HaveLen(2))
+		Expect(run.Status.CompletionTime).NotTo(BeNil())
+		Expect(meta.FindStatusCondition(run.Status.Conditions, orchestrationv1alpha1.RunConditionComplete)).To(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", orchestrationv1alpha1.RunReasonFailed),
+			HaveField("Message", ContainSubstring(string(orchestrationv1alpha1.FailureClassNode))),
+		))
+	})
+
+	It("completes a run whose Job succeeded", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "succeeding-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:      orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Commands: []string{"build"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "succeeding-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "succeeding-run-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+		job.Status.Succeeded = 1
+		Expect(k8sClient.Status().Update(ctx, &job)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseSucceeded))
+		Expect(run.Status.Attempts[0].CompletionTime).To(Equal(run.Status.CompletionTime))
+		Expect(meta.FindStatusCondition(run.Status.Conditions, orchestrationv1alpha1.RunConditionComplete)).To(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", orchestrationv1alpha1.RunReasonSucceeded),
+		))
+	})
+
+	It("only retries the failure classes of the policy", func() {
+		policy := &orchestrationv1alpha1.RetryPolicy{
+			MaxAttempts: 3,
+			On:          []orchestrationv1alpha1.FailureClass{orchestrationv1alpha1.FailureClassPod},
+		}
+		pod := &corev1.Pod{Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  gitCloneContainerName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 128}},
+			}},
+		}}
+		class, container, _ := classifyFailure(pod)
+		Expect(class).To(Equal(orchestrationv1alpha1.FailureClassPod))
+		Expect(container).To(Equal(gitCloneContainerName))
+		Expect(shouldRetry(policy, &orchestrationv1alpha1.RunAttempt{Attempt: 1, FailureClass: class})).To(BeTrue())
+		Expect(shouldRetry(policy, &orchestrationv1alpha1.RunAttempt{Attempt: 3, FailureClass: class})).To(BeFalse())
+
+		pod.Status.InitContainerStatuses = nil
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "dbt",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}},
+		}}
+		class, _, _ = classifyFailure(pod)
+		Expect(class).To(Equal(orchestrationv1alpha1.FailureClassRuntime))
+		Expect(shouldRetry(policy, &orchestrationv1alpha1.RunAttempt{Attempt: 1, FailureClass: class})).To(BeFalse())
+
+		Expect(retryDelay(policy, 1)).To(Equal(30 * time.Second))
+		Expect(retryDelay(policy, 2)).To(Equal(time.Minute))
+		Expect(retryDelay(policy, 5)).To(Equal(8 * time.Minute))
+		Expect(retryDelay(policy, 6)).To(Equal(10 * time.Minute))
+		Expect(retryDelay(policy, 10)).To(Equal(10 * time.Minute))
+
+		policy.Backoff = &metav1.Duration{Duration: time.Hour}
+		policy.MaxDelay = &metav1.Duration{Duration: 15 * time.Minute}
+		Expect(retryDelay(policy, 1)).To(Equal(15 * time.Minute))
+		Expect(retryDelay(policy, 10)).To(Equal(15 * time.Minute))
+	})
+
+	It("resumes multi-step runs at the failed step", func() {
+		steps := []orchestrationv1alpha1.DbtStep{
+			{Name: "deps", Commands: []string{"deps"}},
+			{Name: "seed", Commands: []string{"seed"}},
+			{Name: "build", Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build"}},
+			{Name: "docs", Commands: []string{"docs", "generate"}},
+		}
+
+		retried, err := retrySteps(steps, &orchestrationv1alpha1.RunAttempt{
+			FailedContainer: "dbt-build",
+			FailureClass:    orchestrationv1alpha1.FailureClassNode,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retried).To(Equal([]orchestrationv1alpha1.DbtStep{
+			steps[0],
+			{Name: "build", Commands: []string{"retry"}},
+			steps[3],
+		}))
+
+		retried, err = retrySteps(steps, &orchestrationv1alpha1.RunAttempt{
+			FailedContainer: "dbt-build",
+			FailureClass:    orchestrationv1alpha1.FailureClassPod,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retried[1]).To(Equal(orchestrationv1alpha1.DbtStep{Name: "build", Commands: []string{"build"}}))
+
+		statuses := mergeStepStatuses(steps,
+			[]orchestrationv1alpha1.StepStatus{
+				{Name: "deps", Phase: orchestrationv1alpha1.StepPhaseSucceeded},
+				{Name: "seed", Phase: orchestrationv1alpha1.StepPhaseSucceeded},
+				{Name: "build", Phase: orchestrationv1alpha1.StepPhaseFailed},
+			},
+			stepStatuses(retried, nil, false))
+		Expect(statuses).To(HaveLen(4))
+		Expect(statuses[1].Phase).To(Equal(orchestrationv1alpha1.StepPhaseSucceeded))
+		Expect(statuses[2].Phase).To(Equal(orchestrationv1alpha1.StepPhasePending))
+	})
+})
+
+var _ = Describe("DbtRun timeouts", func() {
+	ctx := context.Background()
+
+	It("ends runs whose Job exceeded its deadline as TimedOut", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "timeout-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:            orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				TimeoutSeconds: ptr.To(int64(3600)),
+				RetryPolicy:    &orchestrationv1alpha1.RetryPolicy{MaxAttempts: 3},
+
+				TerminationGracePeriodSeconds: ptr.To(int64(300)),
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "timeout-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef:     corev1.LocalObjectReference{Name: project.Name},
+				TimeoutSeconds: ptr.To(int64(600)),
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: run.Name + "-dbt-state", Namespace: "default"}})).To(Succeed())
+		})
+
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: run.Name + "-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+		Expect(job.Spec.ActiveDeadlineSeconds).To(Equal(ptr.To(int64(600))))
+		Expect(job.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(ptr.To(int64(300))))
+
+		job.Status.Failed = 1
+		job.Status.StartTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type:   batchv1.JobFailureTarget,
+			Status: corev1.ConditionTrue,
+			Reason: batchv1.JobReasonDeadlineExceeded,
+		}, {
+			Type:   batchv1.JobFailed,
+			Status: corev1.ConditionTrue,
+			Reason: batchv1.JobReasonDeadlineExceeded,
+		}}
+		Expect(k8sClient.Status().Update(ctx, &job)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseTimedOut))
+		Expect(run.Status.CompletionTime).NotTo(BeNil())
+		Expect(run.Status.Attempts).To(HaveLen(1))
+		Expect(run.Status.Attempts[0].Phase).To(Equal(orchestrationv1alpha1.RunPhaseTimedOut))
+		Expect(run.Status.Conditions).To(ContainElement(And(
+			HaveField("Type", orchestrationv1alpha1.RunConditionComplete),
+			HaveField("Reason", orchestrationv1alpha1.RunReasonTimedOut),
+		)))
+	})
+
+	It("lets dbt handle SIGTERM like an interrupt", func() {
+		python, err := exec.LookPath("python3")
+		Expect(err).NotTo(HaveOccurred(), "dbt's entrypoint is tested with python3")
+
+		// A stand-in for dbt that waits to be interrupted and exits like dbt
+		// does once it has cancelled its queries.
+		dir := GinkgoT().TempDir()
+		Expect(os.Mkdir(filepath.Join(dir, "dbt"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "dbt", "__init__.py"), nil, 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "dbt", "__main__.py"), []byte(`import sys, time
+try:
+    print(" ".join(sys.argv), flush=True)
+    time.sleep(60)
+except KeyboardInterrupt:
+    print("cancelled", flush=True)
+    sys.exit(2)
+`), 0o644)).To(Succeed())
+
+		for _, continueOnFailure := range []bool{false, true} {
+			command := dbtCommand(python, continueOnFailure)
+			cmd := exec.Command(command[0], append(command[1:], "run", "--select", "orders")...)
+			cmd.Env = append(os.Environ(), "PYTHONPATH="+dir)
+			stdout, err := cmd.StdoutPipe()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.Start()).To(Succeed())
+			output := bufio.NewReader(stdout)
+
+			Expect(output.ReadString('\n')).To(Equal("dbt run --select orders\n"))
+			Expect(cmd.Process.Signal(syscall.SIGTERM)).To(Succeed())
+			Expect(output.ReadString('\n')).To(Equal("cancelled\n"))
+			err = cmd.Wait()
+			if !continueOnFailure {
+				Expect(err).To(HaveOccurred())
+				Expect(cmd.ProcessState.ExitCode()).To(Equal(2))
+			}
+		}
+	})
+})
+
+var _ = Describe("DbtRun cancellation", func() {
+	ctx := context.Background()
+
+	It("deletes the Job of a cancelled run and keeps the run", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "cancel-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "cancel-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		jobKey := types.NamespacedName{Name: run.Name + "-job", Namespace: "default"}
+		Expect(k8sClient.Get(ctx, jobKey, &batchv1.Job{})).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		run.Spec.Cancel = true
+		Expect(k8sClient.Update(ctx, run)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		Expect(run.Status.CompletionTime).NotTo(BeNil())
+		Expect(run.Status.Attempts[0].Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		Expect(run.Status.Conditions).To(ContainElement(
+			HaveField("Reason", orchestrationv1alpha1.RunReasonCancelled)))
+	})
+
+	It("completes a cancelled run when its pod has stopped, not when the Job was deleted", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "grace-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "grace-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("stopping the pod within its termination grace period")
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       run.Name + "-job-abcde",
+				Namespace:  "default",
+				Labels:     map[string]string{"job-name": run.Name + "-job"},
+				Finalizers: []string{"orchestration.scalecraft.io/test"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "dbt", Image: "dbt"}}},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			pod.Finalizers = nil
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+		})
+		pod.Status.Phase = corev1.PodRunning
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		run.Spec.Cancel = true
+		Expect(k8sClient.Update(ctx, run)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelling))
+		Expect(run.Status.CompletionTime).To(BeNil())
+		Expect(run.Status.Attempts[0].CompletionTime).To(BeNil())
+
+		By("completing the run when dbt has exited")
+		stoppedAt := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+		Expect(pod.DeletionTimestamp).NotTo(BeNil())
+		pod.Status.Phase = corev1.PodFailed
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "dbt",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				ExitCode:   130,
+				FinishedAt: stoppedAt,
+			}},
+		}}
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		Expect(run.Status.CompletionTime.Time).To(BeTemporally("==", stoppedAt.Time))
+		Expect(run.Status.Attempts[0].CompletionTime.Time).To(BeTemporally("==", stoppedAt.Time))
+	})
+
+	It("holds a replacing run until the replaced run's pod has stopped", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "replace-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		replaced := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "replaced-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, replaced)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, replaced)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: replaced.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      replaced.Name + "-job-abcde",
+				Namespace: "default",
+				Labels:    map[string]string{"job-name": replaced.Name + "-job"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "dbt", Image: "dbt"}}},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+		})
+		pod.Status.Phase = corev1.PodRunning
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+		replacing := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "replacing-run",
+				Namespace:   "default",
+				Annotations: map[string]string{replacesAnnotation: replaced.Name},
+			},
+			Spec: orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, replacing)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, replacing)).To(Succeed())
+		})
+
+		By("keeping the replaced run Cancelling while its pod stops")
+		Expect(k8sClient.Get(ctx, key, replaced)).To(Succeed())
+		Expect(replaceRun(ctx, k8sClient, replaced)).To(Succeed())
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(cancelRequeueInterval))
+		Expect(k8sClient.Get(ctx, key, replaced)).To(Succeed())
+		Expect(replaced.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelling))
+
+		result, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(replacing)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(replaceRequeueInterval))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(replacing), replacing)).To(Succeed())
+		Expect(replacing.Status.JobRef).To(BeNil())
+
+		By("ending it once the pod has stopped")
+		pod.Status.Phase = corev1.PodFailed
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, replaced)).To(Succeed())
+		Expect(replaced.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		Expect(replaced.Status.Conditions).To(ContainElement(
+			HaveField("Reason", orchestrationv1alpha1.RunReasonReplaced)))
+		stopping, err := replacedRunsStopping(ctx, k8sClient, replacing)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stopping).To(BeFalse())
+	})
+
+	It("never starts a run cancelled before its Job was created", func() {
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "cancel-pending-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: "missing-project"},
+				Cancel:     true,
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		Expect(run.Status.JobRef).To(BeNil())
+	})
+})
+
+var _ = Describe("DbtRun reruns", func() {
+	ctx := context.Background()
+
+	var project *orchestrationv1alpha1.DbtProject
+	BeforeEach(func() {
+		project = &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "rerun-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+	})
+
+	createRun := func(name string, spec orchestrationv1alpha1.DbtRunSpec, status orchestrationv1alpha1.DbtRunStatus) *orchestrationv1alpha1.DbtRun {
+		spec.ProjectRef = corev1.LocalObjectReference{Name: project.Name}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+		if status.Phase != "" {
+			run.Status = status
+			Expect(k8sClient.Status().Update(ctx, run)).To(Succeed())
+		}
+		return run
+	}
+
+	reconcileRun := func(run *orchestrationv1alpha1.DbtRun) {
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+	}
+
+	It("reruns the failed nodes of the original at its commit", func() {
+		original := createRun("nightly-run", orchestrationv1alpha1.DbtRunSpec{
+			Commands: []string{"build", "--vars", `{"day": "2025-01-01"}`},
+		}, orchestrationv1alpha1.DbtRunStatus{
+			Phase:            orchestrationv1alpha1.RunPhaseFailed,
+			Commit:           "0123456789abcdef0123456789abcdef01234567",
+			StateVolumeClaim: "nightly-run-dbt-state",
+			Attempts: []orchestrationv1alpha1.RunAttempt{{
+				Attempt:         1,
+				Job:             "nightly-run-job",
+				Phase:           orchestrationv1alpha1.RunPhaseFailed,
+				FailureClass:    orchestrationv1alpha1.FailureClassNode,
+				FailedContainer: "dbt",
+			}},
+		})
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly-run-dbt-state", Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+		Expect(controllerutil.SetControllerReference(original, pvc, k8sClient.Scheme())).To(Succeed())
+		Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, pvc)).To(Succeed())
+		})
+		rerun := createRun("nightly-rerun", orchestrationv1alpha1.DbtRunSpec{
+			RerunOf: &orchestrationv1alpha1.RerunReference{Name: original.Name, FromFailure: true},
+		}, orchestrationv1alpha1.DbtRunStatus{})
+
+		reconcileRun(rerun)
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "nightly-rerun-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+
+		Expect(rerun.Spec.Commands).To(BeEmpty())
+		Expect(rerun.Annotations).NotTo(HaveKey(commitAnnotation))
+		Expect(rerun.Status.Effective.Args).To(Equal(original.Spec.Commands))
+		Expect(rerun.Status.Commit).To(Equal(original.Status.Commit))
+		Expect(rerun.Status.RerunOf).To(Equal(original.Name))
+		Expect(rerun.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseRunning))
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Containers[0].Args).To(Equal([]string{"retry"}))
+		Expect(podSpec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CHECKOUT_REF", Value: original.Status.Commit}))
+		Expect(podSpec.Volumes).To(ContainElement(
+			HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", "nightly-run-dbt-state")))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: original.Name, Namespace: "default"}, original)).To(Succeed())
+		Expect(original.Status.Reruns).To(Equal([]string{rerun.Name}))
+
+		By("owning the state volume together with the original")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), pvc)).To(Succeed())
+		Expect(metav1.IsControlledBy(pvc, original)).To(BeTrue())
+		Expect(pvc.OwnerReferences).To(ContainElement(And(
+			HaveField("UID", rerun.UID),
+			HaveField("Controller", BeNil()),
+		)))
+	})
+
+	It("keeps the dbt state of runs without a retry policy", func() {
+		run := createRun("stateful-run", orchestrationv1alpha1.DbtRunSpec{}, orchestrationv1alpha1.DbtRunStatus{})
+
+		reconcileRun(run)
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "stateful-run-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+		Expect(run.Status.StateVolumeClaim).To(Equal("stateful-run-dbt-state"))
+		var pvc corev1.PersistentVolumeClaim
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: run.Status.StateVolumeClaim, Namespace: "default"}, &pvc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &pvc)).To(Succeed())
+		})
+		Expect(metav1.IsControlledBy(&pvc, run)).To(BeTrue())
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DBT_TARGET_PATH", Value: stateMountPath + "/target"}))
+		Expect(podSpec.Volumes).To(ContainElement(
+			HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", run.Status.StateVolumeClaim)))
+	})
+
+	It("fails a rerun from failure when the original's state volume is gone", func() {
+		original := createRun("pruned-run", orchestrationv1alpha1.DbtRunSpec{}, orchestrationv1alpha1.DbtRunStatus{
+			Phase:            orchestrationv1alpha1.RunPhaseFailed,
+			StateVolumeClaim: "pruned-run-dbt-state",
+		})
+		rerun := createRun("pruned-rerun", orchestrationv1alpha1.DbtRunSpec{
+			RerunOf: &orchestrationv1alpha1.RerunReference{Name: original.Name, FromFailure: true},
+		}, orchestrationv1alpha1.DbtRunStatus{})
+
+		reconcileRun(rerun)
+		Expect(rerun.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseError))
+		Expect(rerun.Status.JobRef).To(BeNil())
+		Expect(rerun.Status.Conditions).To(ContainElement(And(
+			HaveField("Reason", orchestrationv1alpha1.RunReasonInvalidRerun),
+			HaveField("Message", ContainSubstring(`state volume "pruned-run-dbt-state"`)),
+		)))
+	})
+
+	It("reruns the commands the original resolved from the project", func() {
+		args := []string{"build", "--select", "tag:daily"}
+		original := createRun("inherited-run", orchestrationv1alpha1.DbtRunSpec{}, orchestrationv1alpha1.DbtRunStatus{
+			Phase:  orchestrationv1alpha1.RunPhaseSucceeded,
+			Commit: "0123456789abcdef0123456789abcdef01234567",
+			Effective: &orchestrationv1alpha1.EffectiveRunConfig{
+				Args:       args,
+				Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build", Select: []string{"tag:daily"}},
+			},
+		})
+		project.Spec.Invocation = &orchestrationv1alpha1.DbtInvocation{Command: "run", Select: []string{"tag:hourly"}}
+		Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+		rerun := createRun("inherited-rerun", orchestrationv1alpha1.DbtRunSpec{
+			RerunOf: &orchestrationv1alpha1.RerunReference{Name: original.Name},
+		}, orchestrationv1alpha1.DbtRunStatus{})
+
+		reconcileRun(rerun)
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "inherited-rerun-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal(args))
+		Expect(rerun.Spec.Invocation).To(BeNil())
+		Expect(rerun.Spec.Commands).To(BeEmpty())
+		Expect(rerun.Status.Effective.Invocation).To(Equal(original.Status.Effective.Invocation))
+	})
+
+	It("refuses to rerun a run that has not finished", func() {
+		original := createRun("running-run", orchestrationv1alpha1.DbtRunSpec{}, orchestrationv1alpha1.DbtRunStatus{
+			Phase: orchestrationv1alpha1.RunPhaseRunning,
+		})
+		rerun := createRun("running-rerun", orchestrationv1alpha1.DbtRunSpec{
+			RerunOf: &orchestrationv1alpha1.RerunReference{Name: original.Name},
+		}, orchestrationv1alpha1.DbtRunStatus{})
+
+		reconcileRun(rerun)
+		Expect(rerun.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseError))
+		Expect(rerun.Status.JobRef).To(BeNil())
+		Expect(rerun.Status.Conditions).To(ContainElement(
+			HaveField("Reason", orchestrationv1alpha1.RunReasonInvalidRerun)))
+	})
+})
+
+var _ = Describe("DbtRun pod templates", func() {
+	ctx := context.Background()
+
+	It("merges the project's and run's pod templates without clobbering the controller's", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "template-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				PodTemplate: &apiextensionsv1.JSON{Raw: []byte(`{
+					"metadata": {"labels": {"team": "data", "orchestration.scalecraft.io/run": "other"}},
+					"spec": {
+						"nodeSelector": {"pool": "dbt"},
+						"imagePullSecrets": [{"name": "registry"}],
+						"restartPolicy": "Always",
+						"initContainers": [{
+							"name": "cloud-sql-proxy",
+							"image": "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2",
+							"restartPolicy": "Always"
+						}],
+						"containers": [{
+							"name": "dbt",
+							"image": "evil:latest",
+							"securityContext": {"allowPrivilegeEscalation": false}
+						}],
+						"volumes": [{"name": "workspace", "hostPath": {"path": "/"}}]
+					}
+				}`)},
+			},
+		}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "template-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				PodTemplate: &orchestrationv1alpha1.RunPodTemplate{
+					Spec: orchestrationv1alpha1.RunPodSpec{
+						NodeSelector:      map[string]string{"zone": "a"},
+						PriorityClassName: "batch-high",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		job, err := reconciler.createJob(ctx, run, project)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+		})
+
+		template := job.Spec.Template
+		Expect(template.Labels).To(HaveKeyWithValue("team", "data"))
+		Expect(template.Labels).To(HaveKeyWithValue("orchestration.scalecraft.io/run", run.Name))
+
+		podSpec := template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "dbt", "zone": "a"}))
+		Expect(podSpec.PriorityClassName).To(Equal("batch-high"))
+		Expect(podSpec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "registry"}}))
+		Expect(podSpec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		Expect(podSpec.InitContainers).To(HaveLen(2))
+		Expect(podSpec.InitContainers[0].Name).To(Equal("cloud-sql-proxy"))
+		Expect(podSpec.InitContainers[1].Name).To(Equal(gitCloneContainerName))
+
+		Expect(podSpec.Containers).To(HaveLen(1))
+		Expect(podSpec.Containers[0].Image).To(Equal("ghcr.io/dbt-labs/dbt-postgres:1.7.0"))
+		Expect(podSpec.Containers[0].Command).To(Equal(dbtCommand("python", false)))
+		Expect(podSpec.Containers[0].SecurityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(false)))
+
+		Expect(podSpec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         "workspace",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+	})
+	It("applies a dbt container of a pod template to the containers of all steps", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "template-steps-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Steps: []orchestrationv1alpha1.DbtStep{
+					{Name: "deps", Commands: []string{"deps"}},
+					{Name: "build", Commands: []string{"build"}},
+				},
+				PodTemplate: &apiextensionsv1.JSON{Raw: []byte(`{
+					"spec": {
+						"containers": [
+							{"name": "dbt", "securityContext": {"allowPrivilegeEscalation": false}},
+							{"name": "typo", "securityContext": {"runAsNonRoot": true}}
+						]
+					}
+				}`)},
+			},
+		}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "template-steps-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		job, err := reconciler.createJob(ctx, run, project)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+		})
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveLen(2))
+		Expect(podSpec.InitContainers[1].Name).To(Equal("dbt-deps"))
+		Expect(podSpec.InitContainers[1].SecurityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(false)))
+		Expect(podSpec.Containers).To(HaveLen(1))
+		Expect(podSpec.Containers[0].Name).To(Equal("dbt-build"))
+		Expect(podSpec.Containers[0].Image).To(Equal("ghcr.io/dbt-labs/dbt-postgres:1.7.0"))
+		Expect(podSpec.Containers[0].SecurityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(false)))
+	})
+})
+
+var _ = Describe("DbtRun overrides", func() {
+	ctx := context.Background()
+
+	It("merges the run's overrides over the project and records them", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "override-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:   orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git", Ref: "main"},
+				Image: "ghcr.io/dbt-labs/dbt-snowflake:1.7.0",
+				Env: []corev1.EnvVar{
+					{Name: "DBT_TARGET", Value: "prod"},
+					{Name: "DBT_THREADS", Value: "4"},
+				},
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		resources := corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+		}
+		envFrom := []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "warehouse"}},
+		}}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "override-run", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				Image:      "ghcr.io/dbt-labs/dbt-snowflake:1.8.0",
+				Env:        []corev1.EnvVar{{Name: "DBT_THREADS", Value: "16"}},
+				EnvFrom:    envFrom,
+				Resources:  &resources,
+				GitRef:     "feature/orders",
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: run.Name + "-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+
+		env := []corev1.EnvVar{
+			{Name: "DBT_TARGET", Value: "prod"},
+			{Name: "DBT_THREADS", Value: "16"},
+		}
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Containers[0].Image).To(Equal(run.Spec.Image))
+		Expect(podSpec.Containers[0].Env).To(Equal(append([]corev1.EnvVar{{Name: "DBT_TARGET_PATH", Value: stateMountPath + "/target"}}, env...)))
+		Expect(podSpec.Containers[0].EnvFrom).To(Equal(envFrom))
+		Expect(podSpec.Containers[0].Resources.Limits.Memory().String()).To(Equal("8Gi"))
+		Expect(podSpec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CHECKOUT_REF", Value: "feature/orders"}))
+
+		Expect(k8sClient.Get(ctx, key, run)).To(Succeed())
+		Expect(run.Status.Effective).NotTo(BeNil())
+		Expect(run.Status.Effective.Image).To(Equal(run.Spec.Image))
+		Expect(run.Status.Effective.GitRef).To(Equal("feature/orders"))
+		Expect(run.Status.Effective.Env).To(Equal(env))
+		Expect(run.Status.Effective.EnvFrom).To(Equal(envFrom))
+		Expect(run.Status.Effective.Resources.Limits.Memory().String()).To(Equal("8Gi"))
+	})
+})
+
+var _ = Describe("Project sources", func() {
+	ctx := context.Background()
+
+	createSourceJob := func(name string, source *orchestrationv1alpha1.ProjectSource) *batchv1.Job {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtProjectSpec{Source: source},
+		}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtRunSpec{
+				ProjectRef: corev1.LocalObjectReference{Name: project.Name},
+				GitRef:     "main",
+			},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		job, err := reconciler.createJob(ctx, run, project)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+		})
+		Expect(job.Spec.Template.Spec.InitContainers[0].Name).To(Equal(fetchSourceContainerName))
+		Expect(job.Spec.Template.Spec.InitContainers[0].Env).NotTo(ContainElement(HaveField("Name", "CHECKOUT_REF")))
+		return job
+	}
+
+	It("requires exactly one of git and source", func() {
+		git := orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"}
+		source := &orchestrationv1alpha1.ProjectSource{
+			ConfigMap: &orchestrationv1alpha1.ConfigMapSource{Name: "analytics"},
+		}
+		for _, spec := range []orchestrationv1alpha1.DbtProjectSpec{
+			{},
+			{Git: git, Source: source},
+		} {
+			project := &orchestrationv1alpha1.DbtProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "one-source-project", Namespace: "default"},
+				Spec:       spec,
+			}
+			Expect(k8sClient.Create(ctx, project)).To(MatchError(ContainSubstring("exactly one of git and source must be set")))
+		}
+
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "one-source-project", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtProjectSpec{Source: source},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+	})
+
+	It("pulls OCI artifacts by digest", func() {
+		digest := "sha256:" + strings.Repeat("a", 64)
+		job := createSourceJob("oci-source", &orchestrationv1alpha1.ProjectSource{
+			Path: "analytics",
+			OCI: &orchestrationv1alpha1.OCISource{
+				Repository: "ghcr.io/example/analytics",
+				Digest:     digest,
+				PullSecret: "ghcr",
+			},
+		})
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers[0].Image).To(Equal(orasImage))
+		Expect(podSpec.InitContainers[0].Command).To(Equal([]string{
+			"oras", "pull", "--output", "/workspace",
+			"--registry-config", "/etc/oci-auth/config.json",
+			"ghcr.io/example/analytics@" + digest,
+		}))
+		Expect(podSpec.Volumes).To(ContainElement(And(
+			HaveField("Name", "oci-auth"),
+			HaveField("Secret.Items", []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}}),
+		)))
+		Expect(podSpec.Containers[0].WorkingDir).To(Equal("/workspace/analytics"))
+	})
+
+	It("mounts ConfigMap and VolumeClaim sources to copy them", func() {
+		items := []corev1.KeyToPath{{Key: "orders.sql", Path: "models/orders.sql"}}
+		job := createSourceJob("configmap-source", &orchestrationv1alpha1.ProjectSource{
+			ConfigMap: &orchestrationv1alpha1.ConfigMapSource{Name: "analytics", Items: items},
+		})
+		Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(And(
+			HaveField("Name", sourceVolumeName),
+			HaveField("ConfigMap.LocalObjectReference", corev1.LocalObjectReference{Name: "analytics"}),
+			HaveField("ConfigMap.Items", items),
+		)))
+		Expect(job.Spec.Template.Spec.Containers[0].WorkingDir).To(Equal("/workspace"))
+
+		job = createSourceJob("volume-claim-source", &orchestrationv1alpha1.ProjectSource{
+			VolumeClaim: &orchestrationv1alpha1.VolumeClaimSource{ClaimName: "analytics", SubPath: "current"},
+		})
+		Expect(job.Spec.Template.Spec.InitContainers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      sourceVolumeName,
+			MountPath: sourceMountPath,
+			SubPath:   "current",
+			ReadOnly:  true,
+		}))
+	})
+
+	It("copies source volumes without ConfigMap bookkeeping", func() {
+		source := GinkgoT().TempDir()
+		data := filepath.Join(source, "..2025_01_01")
+		Expect(os.MkdirAll(filepath.Join(data, "models"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(data, "dbt_project.yml"), []byte("name: analytics\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(data, "models", "orders.sql"), []byte("select 1\n"), 0o644)).To(Succeed())
+		Expect(os.Symlink("..2025_01_01", filepath.Join(source, "..data"))).To(Succeed())
+		Expect(os.Symlink("..data/dbt_project.yml", filepath.Join(source, "dbt_project.yml"))).To(Succeed())
+		Expect(os.Symlink("..data/models", filepath.Join(source, "models"))).To(Succeed())
+
+		dir := GinkgoT().TempDir()
+		cmd := exec.Command("sh", "-c", copySourceScript)
+		cmd.Env = append(os.Environ(), "CHECKOUT_DIR="+dir, "CHECKOUT_SOURCE="+source)
+		out, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+
+		Expect(filepath.Join(dir, "dbt_project.yml")).To(BeARegularFile())
+		Expect(filepath.Join(dir, "models", "orders.sql")).To(BeARegularFile())
+		Expect(filepath.Join(dir, "..data")).NotTo(BeADirectory())
+	})
+
+	Context("with a tarball", func() {
+		var server *httptest.Server
+		var checksum string
+
+		BeforeEach(func() {
+			var archive bytes.Buffer
+			gz := gzip.NewWriter(&archive)
+			tw := tar.NewWriter(gz)
+			content := []byte("name: analytics\n")
+			Expect(tw.WriteHeader(&tar.Header{Name: "analytics-main/dbt_project.yml", Mode: 0o644, Size: int64(len(content))})).To(Succeed())
+			_, err := tw.Write(content)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+			Expect(gz.Close()).To(Succeed())
+
+			sum := sha256.Sum256(archive.Bytes())
+			checksum = hex.EncodeToString(sum[:])
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(archive.Bytes())
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		fetch := func(source *orchestrationv1alpha1.HTTPSource) (string, error) {
+			if _, err := exec.LookPath("wget"); err != nil {
+				Skip("wget is not installed")
+			}
+			job := createSourceJob("http-source-"+source.SHA256[:8], &orchestrationv1alpha1.ProjectSource{HTTP: source})
+			container := job.Spec.Template.Spec.InitContainers[0]
+			Expect(container.Command).To(Equal([]string{"sh", "-c", fetchTarballScript}))
+
+			dir := GinkgoT().TempDir()
+			cmd := exec.Command("sh", "-c", fetchTarballScript)
+			cmd.Env = os.Environ()
+			for _, env := range container.Env {
+				if env.Name == "CHECKOUT_DIR" {
+					env.Value = dir
+				}
+				cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+			}
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return dir, fmt.Errorf("%w: %s", err, out)
+			}
+			return dir, nil
+		}
+
+		It("extracts tarballs matching their checksum", func() {
+			dir, err := fetch(&orchestrationv1alpha1.HTTPSource{URL: server.URL, SHA256: checksum, StripComponents: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Join(dir, "dbt_project.yml")).To(BeARegularFile())
+		})
+
+		It("rejects tarballs with another checksum", func() {
+			dir, err := fetch(&orchestrationv1alpha1.HTTPSource{URL: server.URL, SHA256: strings.Repeat("0", 64)})
+			Expect(err).To(HaveOccurred())
+			Expect(filepath.Join(dir, "analytics-main")).NotTo(BeADirectory())
+		})
+	})
+})
+
+var _ = Describe("Python packages", func() {
+	ctx := context.Background()
+
+	It("installs the adapter and packages into a cached virtualenv", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "packages-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:            orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Adapter:        &orchestrationv1alpha1.DbtAdapter{Name: "duckdb", Version: "1.7.0"},
+				PythonPackages: []string{"pandas==2.2.0"},
+				PackageCache:   &corev1.PersistentVolumeClaimSpec{},
+				Steps: []orchestrationv1alpha1.DbtStep{
+					{Name: "deps", Commands: []string{"deps"}},
+					{Name: "build", Commands: []string{"build"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "packages-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		key := types.NamespacedName{Name: run.Name, Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		var job batchv1.Job
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: run.Name + "-job", Namespace: "default"}, &job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &job)).To(Succeed())
+		})
+
+		requirements := []string{"dbt-duckdb==1.7.0", "pandas==2.2.0"}
+		claimName := packageCacheClaimName(project, packagesHash(defaultPackagesImage, requirements))
+		var pvc corev1.PersistentVolumeClaim
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: claimName, Namespace: "default"}, &pvc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, &pvc)).To(Succeed())
+		})
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+		Expect(pvc.Spec.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}))
+		Expect(pvc.OwnerReferences).To(ConsistOf(HaveField("Name", project.Name)))
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveLen(3))
+		install := podSpec.InitContainers[1]
+		Expect(install.Name).To(Equal(installPackagesContainerName))
+		Expect(install.Image).To(Equal(defaultPackagesImage))
+		Expect(install.Env).To(ContainElement(corev1.EnvVar{Name: "PACKAGES_REQUIREMENTS", Value: "dbt-duckdb==1.7.0\npandas==2.2.0\n"}))
+		Expect(podSpec.InitContainers[2].Command).To(Equal(dbtCommand(packagesPython, false)))
+		Expect(podSpec.Containers[0].Image).To(Equal(defaultPackagesImage))
+		Expect(podSpec.Containers[0].Command).To(Equal(dbtCommand(packagesPython, false)))
+		Expect(podSpec.Volumes).To(ContainElement(corev1.Volume{
+			Name: packagesVolumeName,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			}},
+		}))
+	})
+
+	It("deletes the caches of packages the project no longer installs", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale-packages-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:          orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Adapter:      &orchestrationv1alpha1.DbtAdapter{Name: "duckdb", Version: "1.8.0"},
+				PackageCache: &corev1.PersistentVolumeClaimSpec{},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+
+		createCache := func(hash string) *corev1.PersistentVolumeClaim {
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      packageCacheClaimName(project, hash),
+					Namespace: "default",
+					Labels: map[string]string{
+						"orchestration.scalecraft.io/project": project.Name,
+						packagesHashLabel:                     hash,
+					},
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pvc))).To(Succeed())
+			})
+			return pvc
+		}
+		deleted := func(pvc *corev1.PersistentVolumeClaim) bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), pvc)
+			if errors.IsNotFound(err) {
+				return true
+			}
+			Expect(err).NotTo(HaveOccurred())
+			// The API server protects claims with a finalizer that no
+			// controller removes in the test environment.
+			return pvc.DeletionTimestamp != nil
+		}
+		stale := createCache("0000000000000000")
+		inUse := createCache("1111111111111111")
+
+		By("keeping the cache mounted by an active run")
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale-packages-job", Namespace: "default"},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers:    []corev1.Container{{Name: "dbt", Image: defaultPackagesImage}},
+						Volumes: []corev1.Volume{{
+							Name: packagesVolumeName,
+							VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+								ClaimName: inUse.Name,
+							}},
+						}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, job)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+		})
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale-packages-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+		run.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
+		run.Status.JobRef = &corev1.ObjectReference{Name: job.Name}
+		Expect(k8sClient.Status().Update(ctx, run)).To(Succeed())
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		Expect(reconciler.ensurePackageCache(ctx, project, defaultPackagesImage)).To(Succeed())
+		current := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:      packageCacheClaimName(project, packagesHash(defaultPackagesImage, []string{"dbt-duckdb==1.8.0"})),
+			Namespace: "default",
+		}}
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, current)).To(Succeed())
+		})
+		Expect(deleted(current)).To(BeFalse())
+		Expect(deleted(stale)).To(BeTrue())
+		Expect(deleted(inUse)).To(BeFalse())
+
+		By("deleting it once the run has finished")
+		run.Status.Phase = orchestrationv1alpha1.RunPhaseSucceeded
+		Expect(k8sClient.Status().Update(ctx, run)).To(Succeed())
+		Expect(reconciler.ensurePackageCache(ctx, project, defaultPackagesImage)).To(Succeed())
+		Expect(deleted(inUse)).To(BeTrue())
+		Expect(deleted(current)).To(BeFalse())
+	})
+
+	It("adds Python packages to the dbt of the image without an adapter", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "packages-only-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:            orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				PythonPackages: []string{"pandas==2.2.0"},
+			},
+		}
+		run := &orchestrationv1alpha1.DbtRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "packages-only-run", Namespace: "default"},
+			Spec:       orchestrationv1alpha1.DbtRunSpec{ProjectRef: corev1.LocalObjectReference{Name: project.Name}},
+		}
+		Expect(k8sClient.Create(ctx, run)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, run)).To(Succeed())
+		})
+
+		reconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		job, err := reconciler.createJob(ctx, run, project)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+		})
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers[1].Name).To(Equal(installPackagesContainerName))
+		Expect(podSpec.InitContainers[1].Image).To(Equal("ghcr.io/dbt-labs/dbt-postgres:1.7.0"))
+		Expect(podSpec.Containers[0].Image).To(Equal("ghcr.io/dbt-labs/dbt-postgres:1.7.0"))
+		Expect(podSpec.Containers[0].Command).To(Equal(dbtCommand(packagesPython, false)))
+		Expect(podSpec.Volumes).To(ContainElement(corev1.Volume{
+			Name:         packagesVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+	})
+
+	It("keys package caches by image and package set", func() {
+		hash := packagesHash("python:3.11-slim", []string{"dbt-duckdb==1.7.0", "pandas==2.2.0"})
+		Expect(packagesHash("python:3.11-slim", []string{"pandas==2.2.0", "dbt-duckdb==1.7.0"})).To(Equal(hash))
+		Expect(packagesHash("python:3.12-slim", []string{"dbt-duckdb==1.7.0", "pandas==2.2.0"})).NotTo(Equal(hash))
+		Expect(packagesHash("python:3.11-slim", []string{"dbt-duckdb==1.8.0", "pandas==2.2.0"})).NotTo(Equal(hash))
+	})
+
+	It("installs packages once per virtualenv", func() {
+		if _, err := exec.LookPath("python3"); err != nil {
+			Skip("python3 is not installed")
+		}
+		dir := GinkgoT().TempDir()
+		install := func() string {
+			cmd := exec.Command("python3", "-c", installPackagesProgram)
+			cmd.Env = append(os.Environ(), "PACKAGES_DIR="+dir, "PACKAGES_REQUIREMENTS=")
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return string(out)
+		}
+
+		Expect(install()).To(ContainSubstring("installed packages"))
+		Expect(filepath.Join(dir, "venv", ".installed")).To(BeARegularFile())
+		out, err := exec.Command(filepath.Join(dir, "venv", "bin", "python"), "-c", "import sys; print(sys.prefix)").Output()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(string(out))).To(Equal(filepath.Join(dir, "venv")))
+		Expect(os.ReadFile(filepath.Join(dir, "venv", "pyvenv.cfg"))).To(ContainSubstring("include-system-site-packages = true"))
+
+		Expect(install()).To(ContainSubstring("already installed"))
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
 })
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// gitUsernameKey, gitPasswordKey and gitTokenKey are the keys of a
+	// GitConfig.AuthSecret. A token is used as the password when no password
+	// is given.
+	gitUsernameKey = "username"
+	gitPasswordKey = "password"
+	gitTokenKey    = "token"
+
+	// defaultGitUsername is sent with a token when the Secret has no username;
+	// hosts that authenticate by token ignore it.
+	defaultGitUsername = "x-access-token"
+
+	// gitKnownHostsKey is the key of a GitConfig.SSHKeySecret holding the
+	// server's host keys. It is required; host keys are never accepted
+	// unchecked.
+	gitKnownHostsKey = "known_hosts"
+
+	// gitAuthMountPath and gitSSHMountPath are where the git-clone init
+	// container mounts a GitConfig.AuthSecret and SSHKeySecret.
+	gitAuthMountPath = "/etc/git-auth"
+	gitSSHMountPath  = "/etc/git-ssh"
+
+	gitCloneContainerName = "git-clone"
+
+	// defaultGitDepth is the clone depth when GitConfig.Depth is unset.
+	defaultGitDepth = 1
+
+	// lsRemoteTimeout bounds a single git ls-remote.
+	lsRemoteTimeout = 20 * time.Second
+
+	defaultGitPollInterval = 5 * time.Minute
+	minGitPollInterval     = 30 * time.Second
+
+	// commitAnnotation records the commit a DbtRun was started for.
+	commitAnnotation = "orchestration.scalecraft.io/commit"
+)
+
+// sshPrivateKeyKeys are the keys a GitConfig.SSHKeySecret may store its
+// private key under, in order of preference.
+var sshPrivateKeyKeys = []string{"ssh-privatekey", "id_ed25519", "id_ecdsa", "id_rsa"}
+
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// gitCredentials are the secrets used to reach a repository from the
+// operator. Either may be nil.
+type gitCredentials struct {
+	auth   *corev1.Secret
+	sshKey *corev1.Secret
+}
+
+// lsRemote resolves ref in repository to a commit SHA with git ls-remote.
+// Branches win over tags of the same name and annotated tags resolve to the
+// commit they point at. A ref that already is a commit SHA is returned as is.
+func lsRemote(ctx context.Context, repository, ref string, credentials gitCredentials) (string, error) {
+	if commitSHA.MatchString(ref) {
+		return ref, nil
+	}
+
+	env, cleanup, err := gitEnv(repository, credentials)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(ctx, lsRemoteTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--", repository, ref, ref+"^{}")
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git ls-remote failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	refs := map[string]string{}
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		sha, name, ok := strings.Cut(scanner.Text(), "\t")
+		if ok {
+			refs[name] = sha
+		}
+	}
+
+	candidates := []string{"refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref}
+	if ref == "HEAD" || strings.HasPrefix(ref, "refs/") {
+		candidates = []string{ref + "^{}", ref}
+	}
+	for _, candidate := range candidates {
+		if sha, ok := refs[candidate]; ok {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("ref %q not found in %s", ref, repository)
+}
+
+// gitEnv returns the environment for running git against a repository with
+// the given credentials. Credentials are passed through the environment and
+// private files, never on the command line; cleanup removes the files.
+func gitEnv(repository string, credentials gitCredentials) ([]string, func(), error) {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cleanup := func() {}
+
+	if secret := credentials.auth; secret != nil {
+		username := string(secret.Data[gitUsernameKey])
+		password := string(secret.Data[gitPasswordKey])
+		if password == "" {
+			password = string(secret.Data[gitTokenKey])
+			if username == "" {
+				username = defaultGitUsername
+			}
+		}
+		if password == "" {
+			return nil, nil, fmt.Errorf("secret %s has neither a %q nor a %q key", secret.Name, gitPasswordKey, gitTokenKey)
+		}
+		basic := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http."+gitCredentialScope(repository)+".extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+basic,
+		)
+	}
+
+	if secret := credentials.sshKey; secret != nil {
+		var key []byte
+		for _, name := range sshPrivateKeyKeys {
+			if key = secret.Data[name]; len(key) > 0 {
+				break
+			}
+		}
+		if len(key) == 0 {
+			return nil, nil, fmt.Errorf("secret %s has no SSH private key", secret.Name)
+		}
+		// Without pinned host keys anyone between the operator and the
+		// server could answer with commits of their choosing.
+		if len(secret.Data[gitKnownHostsKey]) == 0 {
+			return nil, nil, fmt.Errorf("secret %s has no %s key, so the host key of the git server cannot be checked", secret.Name, gitKnownHostsKey)
+		}
+
+		dir, err := os.MkdirTemp("", "dagctl-ssh-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+
+		keyFile := filepath.Join(dir, "id")
+		knownHostsFile := filepath.Join(dir, gitKnownHostsKey)
+		if err := os.WriteFile(keyFile, key, 0o600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if err := os.WriteFile(knownHostsFile, secret.Data[gitKnownHostsKey], 0o600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		env = append(env, fmt.Sprintf(
+			"GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes -o BatchMode=yes",
+			keyFile, knownHostsFile))
+	}
+
+	return env, cleanup, nil
+}
+
+// gitCredentialScope returns the URL that HTTP credentials for repository are
+// scoped to: its scheme, host and port. Git only sends them to that host, not
+// to the hosts of submodules or redirects.
+func gitCredentialScope(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || u.Host == "" {
+		return repository
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// gitCheckoutScript checks out a single ref of a repository into
+// $CHECKOUT_DIR and writes the commit to $CHECKOUT_COMMIT_FILE. Its inputs
+// come from the environment and are always quoted, so user-supplied
+// repositories, refs and paths are never interpreted by the shell.
+//
+// The SSH key is copied to a private file first, since ssh rejects keys that
+// others can read and the mounted Secret must be readable by the pod's user.
+// Host keys are checked strictly against the Secret's known_hosts, and the
+// checkout fails without one.
+//
+// A ref is fetched by name, which covers branches, tags and, on servers that
+// allow it, commit SHAs; otherwise all branches and tags are fetched and the
+// ref is looked up locally, as a branch of origin first.
+var gitCheckoutScript = `set -eu
+cd "$CHECKOUT_DIR"
+git init -q .
+git remote add origin -- "$CHECKOUT_REPOSITORY"
+
+if [ -n "${CHECKOUT_SSH_DIR:-}" ]; then
+	for key in ` + strings.Join(sshPrivateKeyKeys, " ") + `; do
+		if [ -f "$CHECKOUT_SSH_DIR/$key" ]; then
+			ssh_dir=$(mktemp -d)
+			(umask 077 && cat "$CHECKOUT_SSH_DIR/$key" > "$ssh_dir/id")
+			known_hosts="$CHECKOUT_SSH_DIR/` + gitKnownHostsKey + `"
+			if [ ! -s "$known_hosts" ]; then
+				echo "error: the SSH key secret has no ` + gitKnownHostsKey + `, so the server's host key cannot be checked" >&2
+				exit 1
+			fi
+			export GIT_SSH_COMMAND="ssh -i $ssh_dir/id -o IdentitiesOnly=yes -o UserKnownHostsFile=$known_hosts -o StrictHostKeyChecking=yes -o BatchMode=yes"
+			break
+		fi
+	done
+fi
+
+depth=
+if [ "${CHECKOUT_DEPTH:-0}" -gt 0 ]; then
+	depth="--depth=$CHECKOUT_DEPTH"
+fi
+filter=
+if [ -n "${CHECKOUT_SPARSE_PATH:-}" ]; then
+	git sparse-checkout set -- "$CHECKOUT_SPARSE_PATH"
+	filter=--filter=blob:none
+fi
+
+if git fetch -q $depth $filter origin -- "$CHECKOUT_REF"; then
+	target=FETCH_HEAD
+else
+	echo "fetching $CHECKOUT_REF by name failed, fetching all branches and tags" >&2
+	git fetch -q $filter origin '+refs/heads/*:refs/remotes/origin/*' '+refs/tags/*:refs/tags/*'
+	target="$CHECKOUT_REF"
+	branch="refs/remotes/origin/${CHECKOUT_REF#refs/heads/}"
+	if git rev-parse -q --verify --end-of-options "$branch^{commit}" >/dev/null; then
+		target="$branch"
+	fi
+fi
+commit=$(git rev-parse --verify --end-of-options "$target^{commit}")
+git -c advice.detachedHead=false checkout -q --detach "$commit"
+
+if [ "${CHECKOUT_SUBMODULES:-}" = true ]; then
+	git submodule update -q --init --recursive $depth
+fi
+
+echo "checked out $commit"
+printf %s "$commit" > "$CHECKOUT_COMMIT_FILE"
+`
+
+// gitCheckoutEnv returns the environment of gitCheckoutScript for checking out
+// ref of the project's repository into dir.
+func gitCheckoutEnv(git *orchestrationv1alpha1.GitConfig, ref, dir string) []corev1.EnvVar {
+	depth := int32(defaultGitDepth)
+	if git.Depth != nil {
+		depth = *git.Depth
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "CHECKOUT_DIR", Value: dir},
+		{Name: "CHECKOUT_REPOSITORY", Value: git.Repository},
+		{Name: "CHECKOUT_REF", Value: ref},
+		{Name: "CHECKOUT_DEPTH", Value: strconv.Itoa(int(depth))},
+		{Name: "CHECKOUT_SUBMODULES", Value: strconv.FormatBool(git.Submodules)},
+		{Name: "CHECKOUT_COMMIT_FILE", Value: corev1.TerminationMessagePathDefault},
+		{Name: "GIT_TERMINAL_PROMPT", Value: "0"},
+	}
+	if path := strings.Trim(git.Path, "/"); git.Sparse && path != "" {
+		env = append(env, corev1.EnvVar{Name: "CHECKOUT_SPARSE_PATH", Value: path})
+	}
+	if git.SSHKeySecret != "" {
+		env = append(env, corev1.EnvVar{Name: "CHECKOUT_SSH_DIR", Value: gitSSHMountPath})
+	}
+	if git.AuthSecret != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "GIT_CONFIG_COUNT", Value: "1"},
+			corev1.EnvVar{Name: "GIT_CONFIG_KEY_0", Value: "credential." + gitCredentialScope(git.Repository) + ".helper"},
+			corev1.EnvVar{Name: "GIT_CONFIG_VALUE_0", Value: gitCredentialHelper(gitAuthMountPath)},
+		)
+	}
+	return env
+}
+
+// checkedOutCommit returns the commit the git-clone init container of pod
+// reported, if it has finished.
+func checkedOutCommit(pod *corev1.Pod) string {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != gitCloneContainerName || status.State.Terminated == nil {
+			continue
+		}
+		if commit := strings.TrimSpace(status.State.Terminated.Message); commitSHA.MatchString(commit) {
+			return commit
+		}
+	}
+	return ""
+}
+
+// gitCredentialHelper returns a git credential helper that answers from the
+// files of an AuthSecret mounted at dir, with the same fallbacks as gitEnv.
+// The secret is read when git asks for it, so it appears neither on a command
+// line nor in the pod spec.
+func gitCredentialHelper(dir string) string {
+	return fmt.Sprintf(`!f() { test "$1" = get || return 0; `+
+		`u=$(cat %[1]s/%[2]s 2>/dev/null); p=$(cat %[1]s/%[3]s 2>/dev/null); `+
+		`if [ -z "$p" ]; then p=$(cat %[1]s/%[4]s 2>/dev/null); u=${u:-%[5]s}; fi; `+
+		`echo "username=$u"; echo "password=$p"; }; f`,
+		dir, gitUsernameKey, gitPasswordKey, gitTokenKey, defaultGitUsername)
+}
+
+// gitPollInterval returns the effective interval of a git poll.
+func gitPollInterval(poll *orchestrationv1alpha1.GitPoll) time.Duration {
+	if poll.Interval == nil {
+		return defaultGitPollInterval
+	}
+	return max(poll.Interval.Duration, minGitPollInterval)
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// gitPollScanInterval is how often the GitPoller looks for projects
+	// whose poll is due.
+	gitPollScanInterval = 10 * time.Second
+
+	// defaultMaxConcurrentGitPolls bounds the git ls-remote calls a
+	// GitPoller runs at once unless MaxConcurrentPolls says otherwise.
+	defaultMaxConcurrentGitPolls = 4
+)
+
+// GitPoller checks the repositories of projects that set git.poll for new
+// commits. It runs beside the DbtProject controller rather than in its
+// reconciles so that a slow or unreachable git host only delays the polls of
+// its own projects. It records the commit Ref points at in the project's
+// status.resolvedCommit, along with lastPollTime and the RefResolved
+// condition; the controller starts a GitChange run once resolvedCommit moves
+// past status.triggeredCommit.
+type GitPoller struct {
+	client.Client
+	// APIReader reads Secrets and the projects it updates uncached; it
+	// defaults to Client.
+	APIReader client.Reader
+	Clock     clock.PassiveClock
+	// MaxConcurrentPolls bounds the git ls-remote calls running at once.
+	MaxConcurrentPolls int
+
+	// polling holds the keys of the projects being polled.
+	polling sync.Map
+}
+
+// SetupWithManager runs the poller in the manager's leader.
+func (p *GitPoller) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(p)
+}
+
+// NeedLeaderElection makes only the leader poll, as only it starts runs.
+func (p *GitPoller) NeedLeaderElection() bool {
+	return true
+}
+
+// Start polls due projects until ctx is done.
+func (p *GitPoller) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("git-poller")
+
+	slots := make(chan struct{}, p.maxConcurrentPolls())
+	var polls sync.WaitGroup
+	defer polls.Wait()
+
+	ticker := time.NewTicker(gitPollScanInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.pollDue(ctx, slots, &polls); err != nil {
+			log.Error(err, "Failed to list projects to poll")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollDue starts a poll of every project whose poll interval has passed and
+// that is not being polled already. At most cap(slots) polls run at once.
+func (p *GitPoller) pollDue(ctx context.Context, slots chan struct{}, polls *sync.WaitGroup) error {
+	var projects orchestrationv1alpha1.DbtProjectList
+	if err := p.List(ctx, &projects); err != nil {
+		return err
+	}
+
+	now := p.now()
+	for i := range projects.Items {
+		project := &projects.Items[i]
+		if !gitPollDue(project, now) {
+			continue
+		}
+		key := client.ObjectKeyFromObject(project)
+		if _, polling := p.polling.LoadOrStore(key, true); polling {
+			continue
+		}
+
+		polls.Add(1)
+		go func() {
+			defer polls.Done()
+			defer p.polling.Delete(key)
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			if err := p.Poll(ctx, key); err != nil {
+				log.FromContext(ctx).Error(err, "Failed to record git poll", "project", key)
+			}
+		}()
+	}
+	return nil
+}
+
+// Poll resolves the ref of a project's repository and records the commit,
+// or why it could not be resolved, in the project's status.
+func (p *GitPoller) Poll(ctx context.Context, key client.ObjectKey) error {
+	var project orchestrationv1alpha1.DbtProject
+	if err := p.reader().Get(ctx, key, &project); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	git := projectGit(&project)
+	if git == nil || git.Poll == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:   orchestrationv1alpha1.ProjectConditionRefResolved,
+		Status: metav1.ConditionTrue,
+		Reason: orchestrationv1alpha1.ProjectReasonPollSucceeded,
+	}
+	commit, err := resolveCommit(ctx, p.reader(), project.Namespace, git)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to poll git repository", "project", key)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = orchestrationv1alpha1.ProjectReasonPollFailed
+		condition.Message = err.Error()
+	} else {
+		condition.Message = fmt.Sprintf("%s is at %s", getGitRef(git.Ref), commit)
+	}
+	polled := metav1.NewTime(p.now())
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := p.reader().Get(ctx, key, &project); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		project.Status.LastPollTime = &polled
+		if commit != "" {
+			project.Status.ResolvedCommit = commit
+		}
+		condition.ObservedGeneration = project.Generation
+		meta.SetStatusCondition(&project.Status.Conditions, condition)
+		return p.Status().Update(ctx, &project)
+	})
+}
+
+// gitPollDue reports whether the repository of a project that polls it is
+// due for a check at now. Suspended projects are not polled.
+func gitPollDue(project *orchestrationv1alpha1.DbtProject, now time.Time) bool {
+	git := projectGit(project)
+	if git == nil || git.Poll == nil || project.Spec.Suspend {
+		return false
+	}
+	last := project.Status.LastPollTime
+	return last == nil || !now.Before(last.Add(gitPollInterval(git.Poll)))
+}
+
+// resolveCommit looks up the commit the ref of a project's git repository
+// points at, using its credentials.
+func resolveCommit(ctx context.Context, reader client.Reader, namespace string, git *orchestrationv1alpha1.GitConfig) (string, error) {
+	var credentials gitCredentials
+	var err error
+	if credentials.auth, err = getSecret(ctx, reader, namespace, git.AuthSecret); err != nil {
+		return "", err
+	}
+	if credentials.sshKey, err = getSecret(ctx, reader, namespace, git.SSHKeySecret); err != nil {
+		return "", err
+	}
+
+	return lsRemote(ctx, git.Repository, getGitRef(git.Ref), credentials)
+}
+
+// getSecret returns the named Secret, or nil when name is empty.
+func getSecret(ctx context.Context, reader client.Reader, namespace, name string) (*corev1.Secret, error) {
+	if name == "" {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get secret %q: %w", name, err)
+	}
+	return &secret, nil
+}
+
+func (p *GitPoller) maxConcurrentPolls() int {
+	if p.MaxConcurrentPolls <= 0 {
+		return defaultMaxConcurrentGitPolls
+	}
+	return p.MaxConcurrentPolls
+}
+
+func (p *GitPoller) reader() client.Reader {
+	return apiReader(p.APIReader, p.Client)
+}
+
+func (p *GitPoller) now() time.Time {
+	if p.Clock == nil {
+		return time.Now()
+	}
+	return p.Clock.Now()
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const defaultDbtCommand = "run"
+
+// dbtArgs returns the arguments dbt is started with for a run. Raw commands
+// of the run win; otherwise the run's invocation is merged over the
+// project's, and a project with raw commands only falls back to those.
+func dbtArgs(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) ([]string, error) {
+	switch {
+	case len(run.Spec.Commands) > 0:
+		return run.Spec.Commands, nil
+	case run.Spec.Invocation != nil || project.Spec.Invocation != nil:
+		return invocationArgs(mergeInvocations(project.Spec.Invocation, run.Spec.Invocation))
+	case len(project.Spec.Commands) > 0:
+		return project.Spec.Commands, nil
+	}
+	return []string{defaultDbtCommand}, nil
+}
+
+// resolveCommands returns what the run's Jobs run: the dbt arguments and the
+// invocation they were built from, or the steps of a multi-step run. Once
+// recorded in the run's effective configuration they are pinned, so that
+// retries and reruns repeat them.
+func resolveCommands(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) ([]string, *orchestrationv1alpha1.DbtInvocation, []orchestrationv1alpha1.DbtStep, error) {
+	if pinned := run.Status.Effective; pinned != nil && (len(pinned.Args) > 0 || len(pinned.Steps) > 0) {
+		return pinned.Args, pinned.Invocation, pinned.Steps, nil
+	}
+	if steps := runSteps(run, project); len(steps) > 0 {
+		return nil, nil, stepsWithDefaults(steps, project.Spec.Invocation), nil
+	}
+	var invocation *orchestrationv1alpha1.DbtInvocation
+	if len(run.Spec.Commands) == 0 && (run.Spec.Invocation != nil || project.Spec.Invocation != nil) {
+		merged := mergeInvocations(project.Spec.Invocation, run.Spec.Invocation)
+		invocation = &merged
+	}
+	args, err := dbtArgs(run, project)
+	return args, invocation, nil, err
+}
+
+// mergeInvocations returns base with the fields set in override replacing
+// its own. Vars are merged by name.
+func mergeInvocations(base, override *orchestrationv1alpha1.DbtInvocation) orchestrationv1alpha1.DbtInvocation {
+	var merged orchestrationv1alpha1.DbtInvocation
+	if base != nil {
+		base.DeepCopyInto(&merged)
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.Command != "" {
+		merged.Command = override.Command
+	}
+	if override.Select != nil {
+		merged.Select = override.Select
+	}
+	if override.Exclude != nil {
+		merged.Exclude = override.Exclude
+	}
+	if override.Selector != "" {
+		merged.Selector = override.Selector
+	}
+	if len(override.Vars) > 0 {
+		if merged.Vars == nil {
+			merged.Vars = map[string]apiextensionsv1.JSON{}
+		}
+		maps.Copy(merged.Vars, override.Vars)
+	}
+	if override.Target != "" {
+		merged.Target = override.Target
+	}
+	if override.FullRefresh != nil {
+		merged.FullRefresh = override.FullRefresh
+	}
+	if override.Threads != nil {
+		merged.Threads = override.Threads
+	}
+	if override.FailFast != nil {
+		merged.FailFast = override.FailFast
+	}
+	return merged
+}
+
+// invocationArgs renders an invocation as dbt command line arguments.
+func invocationArgs(invocation orchestrationv1alpha1.DbtInvocation) ([]string, error) {
+	command := invocation.Command
+	if command == "" {
+		command = defaultDbtCommand
+	}
+	args := []string{command}
+
+	if len(invocation.Select) > 0 {
+		args = append(append(args, "--select"), invocation.Select...)
+	}
+	if len(invocation.Exclude) > 0 {
+		args = append(append(args, "--exclude"), invocation.Exclude...)
+	}
+	if invocation.Selector != "" {
+		args = append(args, "--selector", invocation.Selector)
+	}
+	if len(invocation.Vars) > 0 {
+		vars, err := json.Marshal(invocation.Vars)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vars: %w", err)
+		}
+		args = append(args, "--vars", string(vars))
+	}
+	if invocation.Target != "" {
+		args = append(args, "--target", invocation.Target)
+	}
+	if invocation.FullRefresh != nil && *invocation.FullRefresh {
+		args = append(args, "--full-refresh")
+	}
+	if invocation.Threads != nil {
+		args = append(args, "--threads", strconv.Itoa(int(*invocation.Threads)))
+	}
+	if invocation.FailFast != nil && *invocation.FailFast {
+		args = append(args, "--fail-fast")
+	}
+	return args, nil
+}
+
+// errVarsConflict is returned when vars are to be passed to dbt arguments
+// that already set them.
+var errVarsConflict = errors.New("vars cannot be combined with commands that already pass --vars")
+
+// appendVars returns a copy of args that passes the JSON-encoded vars to dbt.
+func appendVars(args []string, vars string) ([]string, error) {
+	if slices.ContainsFunc(args, func(arg string) bool { return arg == "--vars" || strings.HasPrefix(arg, "--vars=") }) {
+		return nil, errVarsConflict
+	}
+	return append(slices.Clip(args), "--vars", vars), nil
+}
+
+// invocationVars converts vars to their invocation form.
+func invocationVars[V any](vars map[string]V) (map[string]apiextensionsv1.JSON, error) {
+	converted := make(map[string]apiextensionsv1.JSON, len(vars))
+	for name, value := range vars {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid var %q: %w", name, err)
+		}
+		converted[name] = apiextensionsv1.JSON{Raw: raw}
+	}
+	return converted, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025 ScaleCraft.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/utils/ptr"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+var _ = Describe("dbt invocations", func() {
+	project := func(commands []string, invocation *orchestrationv1alpha1.DbtInvocation) *orchestrationv1alpha1.DbtProject {
+		return &orchestrationv1alpha1.DbtProject{Spec: orchestrationv1alpha1.DbtProjectSpec{Commands: commands, Invocation: invocation}}
+	}
+	run := func(commands []string, invocation *orchestrationv1alpha1.DbtInvocation) *orchestrationv1alpha1.DbtRun {
+		return &orchestrationv1alpha1.DbtRun{Spec: orchestrationv1alpha1.DbtRunSpec{Commands: commands, Invocation: invocation}}
+	}
+
+	It("renders every field as dbt arguments", func() {
+		args, err := invocationArgs(orchestrationv1alpha1.DbtInvocation{
+			Command:     "build",
+			Select:      []string{"tag:daily", "orders+"},
+			Exclude:     []string{"tag:slow"},
+			Selector:    "nightly",
+			Vars:        map[string]apiextensionsv1.JSON{"days": {Raw: []byte("3")}, "region": {Raw: []byte(`"eu"`)}},
+			Target:      "prod",
+			FullRefresh: ptr.To(true),
+			Threads:     ptr.To(int32(8)),
+			FailFast:    ptr.To(true),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{
+			"build",
+			"--select", "tag:daily", "orders+",
+			"--exclude", "tag:slow",
+			"--selector", "nightly",
+			"--vars", `{"days":3,"region":"eu"}`,
+			"--target", "prod",
+			"--full-refresh",
+			"--threads", "8",
+			"--fail-fast",
+		}))
+	})
+
+	It("merges the run's invocation over the project's", func() {
+		args, err := dbtArgs(
+			run(nil, &orchestrationv1alpha1.DbtInvocation{
+				Select:      []string{"orders"},
+				Vars:        map[string]apiextensionsv1.JSON{"region": {Raw: []byte(`"us"`)}},
+				FullRefresh: ptr.To(false),
+			}),
+			project(nil, &orchestrationv1alpha1.DbtInvocation{
+				Command:     "build",
+				Select:      []string{"tag:daily"},
+				Vars:        map[string]apiextensionsv1.JSON{"region": {Raw: []byte(`"eu"`)}, "days": {Raw: []byte("1")}},
+				Target:      "prod",
+				FullRefresh: ptr.To(true),
+			}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"build", "--select", "orders", "--vars", `{"days":1,"region":"us"}`, "--target", "prod"}))
+	})
+
+	It("merges step invocations over the project's", func() {
+		p := project(nil, &orchestrationv1alpha1.DbtInvocation{
+			Vars:   map[string]apiextensionsv1.JSON{"region": {Raw: []byte(`"eu"`)}, "days": {Raw: []byte("1")}},
+			Target: "prod",
+		})
+		p.Spec.Steps = []orchestrationv1alpha1.DbtStep{
+			{Name: "deps", Commands: []string{"deps"}},
+			{Name: "build", Invocation: &orchestrationv1alpha1.DbtInvocation{
+				Command: "build",
+				Vars:    map[string]apiextensionsv1.JSON{"region": {Raw: []byte(`"us"`)}},
+			}},
+		}
+
+		_, _, steps, err := resolveCommands(run(nil, nil), p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stepArgs(steps[0])).To(Equal([]string{"deps"}))
+		Expect(stepArgs(steps[1])).To(Equal([]string{"build", "--vars", `{"days":1,"region":"us"}`, "--target", "prod"}))
+		Expect(p.Spec.Steps[1].Invocation.Target).To(BeEmpty())
+
+		r := run(nil, nil)
+		r.Spec.Steps = []orchestrationv1alpha1.DbtStep{{Name: "test", Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "test"}}}
+		_, _, steps, err = resolveCommands(r, p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stepArgs(steps[0])).To(Equal([]string{"test", "--vars", `{"days":1,"region":"eu"}`, "--target", "prod"}))
+	})
+
+	DescribeTable("falls back between raw commands and invocations",
+		func(r *orchestrationv1alpha1.DbtRun, p *orchestrationv1alpha1.DbtProject, expected ...string) {
+			Expect(dbtArgs(r, p)).To(Equal(expected))
+		},
+		Entry("raw run commands win", run([]string{"test"}, nil), project(nil, &orchestrationv1alpha1.DbtInvocation{Command: "build"}), "test"),
+		Entry("the project's invocation", run(nil, nil), project(nil, &orchestrationv1alpha1.DbtInvocation{Target: "dev"}), "run", "--target", "dev"),
+		Entry("the project's raw commands", run(nil, nil), project([]string{"seed"}, nil), "seed"),
+		Entry("a run invocation without project defaults", run(nil, &orchestrationv1alpha1.DbtInvocation{Command: "test"}), project([]string{"seed"}, nil), "test"),
+		Entry("the default", run(nil, nil), project(nil, nil), "run"),
+	)
+})
@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	installPackagesContainerName = "install-packages"
+
+	packagesVolumeName = "dbt-packages"
+	packagesMountPath  = "/opt/dbt-packages"
+
+	// packagesPython is the python of the virtualenv the packages are
+	// installed into, which runs dbt instead of the image's.
+	packagesPython = packagesMountPath + "/venv/bin/python"
+
+	// defaultPackagesImage runs dbt when an adapter is installed and the
+	// project sets no image; it only needs to provide Python.
+	defaultPackagesImage = "python:3.11-slim"
+
+	packagesHashLabel = "orchestration.scalecraft.io/packages-hash"
+)
+
+// installPackagesProgram installs $PACKAGES_REQUIREMENTS into a virtualenv
+// at $PACKAGES_DIR/venv unless a previous run already did. The virtualenv
+// sees the packages of the image, so that Python packages can be added to an
+// image that provides dbt; installed packages take precedence. It is built
+// next to its final location and renamed into place once complete, so that
+// runs sharing a cache never see a partial install; a run that loses the
+// race discards its own.
+const installPackagesProgram = `import os, shutil, subprocess, sys, venv
+root = os.environ["PACKAGES_DIR"]
+target = os.path.join(root, "venv")
+if os.path.exists(os.path.join(target, ".installed")):
+    print("packages are already installed")
+    sys.exit(0)
+staging = os.path.join(root, "venv." + os.environ.get("HOSTNAME", str(os.getpid())))
+shutil.rmtree(staging, ignore_errors=True)
+venv.create(staging, system_site_packages=True, with_pip=True)
+requirements = os.path.join(staging, "requirements.txt")
+with open(requirements, "w") as f:
+    f.write(os.environ["PACKAGES_REQUIREMENTS"])
+subprocess.run([os.path.join(staging, "bin", "python"), "-m", "pip", "install", "--quiet",
+                "--no-cache-dir", "--disable-pip-version-check", "-r", requirements], check=True)
+open(os.path.join(staging, ".installed"), "w").close()
+try:
+    os.rename(staging, target)
+except OSError:
+    shutil.rmtree(staging)
+print("installed packages")
+`
+
+// pythonRequirements returns the pip requirements of the project's adapter
+// and Python packages, or nil when it installs none.
+func pythonRequirements(project *orchestrationv1alpha1.DbtProject) []string {
+	var requirements []string
+	if adapter := project.Spec.Adapter; adapter != nil {
+		requirements = append(requirements, fmt.Sprintf("dbt-%s==%s", adapter.Name, adapter.Version))
+	}
+	return append(requirements, project.Spec.PythonPackages...)
+}
+
+// packagesHash identifies the virtualenv built from requirements with the
+// Python of image.
+func packagesHash(image string, requirements []string) string {
+	sorted := slices.Sorted(slices.Values(requirements))
+	sum := sha256.Sum256([]byte(image + "\n" + strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func packageCacheClaimName(project *orchestrationv1alpha1.DbtProject, hash string) string {
+	return fmt.Sprintf("%s-packages-%s", project.Name, hash)
+}
+
+// ensurePackageCache creates the project's package cache PVC for the
+// packages of image unless it exists, and deletes the caches of packages the
+// project no longer installs. Caches belong to the project and are deleted
+// with it. Concurrent runs mount the same cache from any node, so it is
+// ReadWriteMany unless the spec asks otherwise.
+func (r *DbtRunReconciler) ensurePackageCache(ctx context.Context, project *orchestrationv1alpha1.DbtProject, image string) error {
+	requirements := pythonRequirements(project)
+	if project.Spec.PackageCache == nil || len(requirements) == 0 {
+		return r.pruneStalePackageCaches(ctx, project, "")
+	}
+
+	spec := *project.Spec.PackageCache.DeepCopy()
+	if len(spec.AccessModes) == 0 {
+		spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	}
+	if _, ok := spec.Resources.Requests[corev1.ResourceStorage]; !ok {
+		if spec.Resources.Requests == nil {
+			spec.Resources.Requests = corev1.ResourceList{}
+		}
+		spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("2Gi")
+	}
+
+	hash := packagesHash(image, requirements)
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      packageCacheClaimName(project, hash),
+			Namespace: project.Namespace,
+			Labels: map[string]string{
+				"orchestration.scalecraft.io/project": project.Name,
+				packagesHashLabel:                     hash,
+			},
+		},
+		Spec: spec,
+	}
+	if err := controllerutil.SetControllerReference(project, pvc, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, pvc); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create PersistentVolumeClaim %s: %w", pvc.Name, err)
+		}
+	} else {
+		log.FromContext(ctx).Info("Created PersistentVolumeClaim", "pvc", pvc.Name)
+	}
+	return r.pruneStalePackageCaches(ctx, project, hash)
+}
+
+// pruneStalePackageCaches deletes the project's package caches whose
+// packages-hash is not hash, such as those of packages it installed before,
+// unless a run that has not finished uses them: runs whose Job exists keep
+// the caches its pods mount, and runs yet to start the cache of their image.
+func (r *DbtRunReconciler) pruneStalePackageCaches(ctx context.Context, project *orchestrationv1alpha1.DbtProject, hash string) error {
+	var caches corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &caches, client.InNamespace(project.Namespace),
+		client.MatchingLabels{"orchestration.scalecraft.io/project": project.Name},
+		client.HasLabels{packagesHashLabel}); err != nil {
+		return fmt.Errorf("failed to list package caches: %w", err)
+	}
+	var stale []corev1.PersistentVolumeClaim
+	for _, cache := range caches.Items {
+		if cache.Labels[packagesHashLabel] != hash && cache.DeletionTimestamp == nil {
+			stale = append(stale, cache)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	activeRuns, err := listActiveRuns(ctx, r.Client, project)
+	if err != nil {
+		return fmt.Errorf("failed to list active runs: %w", err)
+	}
+	inUse := map[string]bool{}
+	requirements := pythonRequirements(project)
+	for i := range activeRuns {
+		run := &activeRuns[i]
+		if run.Status.JobRef == nil {
+			if len(requirements) > 0 {
+				inUse[packageCacheClaimName(project, packagesHash(effectiveConfig(run, project).Image, requirements))] = true
+			}
+			continue
+		}
+		var job batchv1.Job
+		if err := r.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: run.Status.JobRef.Name}, &job); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get Job %s: %w", run.Status.JobRef.Name, err)
+		}
+		for _, volume := range job.Spec.Template.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				inUse[volume.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	for i := range stale {
+		if inUse[stale[i].Name] {
+			continue
+		}
+		if err := r.Delete(ctx, &stale[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete PersistentVolumeClaim %s: %w", stale[i].Name, err)
+		}
+		log.FromContext(ctx).Info("Deleted stale package cache", "pvc", stale[i].Name)
+	}
+	return nil
+}
+
+// packagesContainer returns the init container installing requirements with
+// the Python of the run's image, and the volume holding them: the project's
+// package cache, or an emptyDir without one.
+func packagesContainer(project *orchestrationv1alpha1.DbtProject, config orchestrationv1alpha1.EffectiveRunConfig, requirements []string) (corev1.Container, corev1.Volume) {
+	container := corev1.Container{
+		Name:    installPackagesContainerName,
+		Image:   config.Image,
+		Command: []string{"python", "-c", installPackagesProgram},
+		Env: []corev1.EnvVar{
+			{Name: "PACKAGES_DIR", Value: packagesMountPath},
+			{Name: "PACKAGES_REQUIREMENTS", Value: strings.Join(requirements, "\n") + "\n"},
+		},
+		Resources: config.Resources,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      packagesVolumeName,
+				MountPath: packagesMountPath,
+			},
+		},
+	}
+
+	volume := corev1.Volume{
+		Name:         packagesVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	if project.Spec.PackageCache != nil {
+		volume.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: packageCacheClaimName(project, packagesHash(config.Image, requirements)),
+			},
+		}
+	}
+	return container, volume
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// applyPodTemplates strategically merges the pod templates of the project
+// and then of the run into template, the pod template generated for a run.
+// What the controller relies on is restored from the generated template.
+func applyPodTemplates(template *corev1.PodTemplateSpec, project *orchestrationv1alpha1.DbtProject, run *orchestrationv1alpha1.DbtRun) error {
+	var patches [][]byte
+	if project.Spec.PodTemplate != nil {
+		patches = append(patches, project.Spec.PodTemplate.Raw)
+	}
+	if run.Spec.PodTemplate != nil {
+		patch, err := json.Marshal(run.Spec.PodTemplate)
+		if err != nil {
+			return err
+		}
+		patches = append(patches, patch)
+	}
+	if len(patches) == 0 {
+		return nil
+	}
+
+	merged, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	for _, patch := range patches {
+		if patch, err = expandDbtContainer(patch, template); err != nil {
+			return fmt.Errorf("invalid pod template: %w", err)
+		}
+		if merged, err = strategicpatch.StrategicMergePatch(merged, patch, corev1.PodTemplateSpec{}); err != nil {
+			return fmt.Errorf("invalid pod template: %w", err)
+		}
+	}
+	var result corev1.PodTemplateSpec
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return fmt.Errorf("invalid pod template: %w", err)
+	}
+
+	protectPodTemplate(&result, template)
+	*template = result
+	return nil
+}
+
+// expandDbtContainer rewrites the dbt container of patch into one for each
+// step container of template, which has no dbt container when the run has
+// steps, so that it applies to all of them. Step containers patch lists by
+// name keep their own.
+func expandDbtContainer(patch []byte, template *corev1.PodTemplateSpec) ([]byte, error) {
+	if slices.ContainsFunc(template.Spec.Containers, func(c corev1.Container) bool { return c.Name == "dbt" }) {
+		return patch, nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal(patch, &object); err != nil {
+		return nil, err
+	}
+	spec, _ := object["spec"].(map[string]any)
+	containers, _ := spec["containers"].([]any)
+	i := slices.IndexFunc(containers, named("dbt"))
+	if i < 0 {
+		return patch, nil
+	}
+	dbt := containers[i].(map[string]any)
+	spec["containers"] = slices.Delete(containers, i, i+1)
+
+	expand := func(key string, generated []corev1.Container) {
+		list, _ := spec[key].([]any)
+		for _, container := range generated {
+			if !isDbtContainer(container.Name) || slices.ContainsFunc(list, named(container.Name)) {
+				continue
+			}
+			step := maps.Clone(dbt)
+			step["name"] = container.Name
+			list = append(list, step)
+		}
+		if len(list) > 0 {
+			spec[key] = list
+		}
+	}
+	expand("initContainers", template.Spec.InitContainers)
+	expand("containers", template.Spec.Containers)
+	return json.Marshal(object)
+}
+
+// named returns whether a container of a decoded patch is called name.
+func named(name string) func(any) bool {
+	return func(c any) bool {
+		container, _ := c.(map[string]any)
+		return container["name"] == name
+	}
+}
+
+// protectPodTemplate restores the parts of generated that a pod template must
+// not override in merged. Added init containers run before the controller's,
+// so that sidecars are up before the checkout.
+func protectPodTemplate(merged, generated *corev1.PodTemplateSpec) {
+	if merged.Labels == nil {
+		merged.Labels = map[string]string{}
+	}
+	maps.Copy(merged.Labels, generated.Labels)
+	if merged.Annotations == nil {
+		merged.Annotations = map[string]string{}
+	}
+	maps.Copy(merged.Annotations, generated.Annotations)
+
+	spec := &merged.Spec
+	spec.RestartPolicy = generated.Spec.RestartPolicy
+	spec.InitContainers = protectContainers(spec.InitContainers, generated.Spec.InitContainers, true)
+	spec.Containers = protectContainers(spec.Containers, generated.Spec.Containers, false)
+
+	volumes := slices.Clone(generated.Spec.Volumes)
+	for _, volume := range spec.Volumes {
+		if !slices.ContainsFunc(generated.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == volume.Name }) {
+			volumes = append(volumes, volume)
+		}
+	}
+	spec.Volumes = volumes
+}
+
+// protectContainers returns the generated containers, each with the
+// overrides of its merged counterpart except for what the controller sets,
+// and the containers merged added before or after them. Added containers
+// without an image could never start; they only carried overrides for
+// containers that do not exist and are dropped.
+func protectContainers(merged, generated []corev1.Container, addedFirst bool) []corev1.Container {
+	var protected, added []corev1.Container
+	for _, container := range generated {
+		if i := slices.IndexFunc(merged, func(c corev1.Container) bool { return c.Name == container.Name }); i >= 0 {
+			override := merged[i]
+			override.Image = container.Image
+			override.Command = container.Command
+			override.Args = container.Args
+			override.WorkingDir = container.WorkingDir
+			override.Env = container.Env
+			override.EnvFrom = container.EnvFrom
+			override.Resources = container.Resources
+			override.VolumeMounts = container.VolumeMounts
+			container = override
+		}
+		protected = append(protected, container)
+	}
+	for _, container := range merged {
+		if container.Image != "" && !slices.ContainsFunc(generated, func(c corev1.Container) bool { return c.Name == container.Name }) {
+			added = append(added, container)
+		}
+	}
+	if addedFirst {
+		return append(added, protected...)
+	}
+	return append(protected, added...)
+}
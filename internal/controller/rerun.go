@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// resolveRerun prepares a rerun before its first Job: it pins the dbt
+// commands the original resolved, unless it has its own, and the commit the
+// original checked out in its status. A rerun from failure also takes over
+// the original's state volume and resumes its last attempt; the rerun becomes
+// an owner of the volume, so deleting the original does not delete it while
+// the rerun needs it. Both runs are linked in their statuses. It returns a
+// message when the original cannot be rerun.
+func (r *DbtRunReconciler) resolveRerun(ctx context.Context, run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) (string, error) {
+	rerunOf := run.Spec.RerunOf
+
+	var original orchestrationv1alpha1.DbtRun
+	if err := r.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: rerunOf.Name}, &original); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("DbtRun %q not found", rerunOf.Name), nil
+		}
+		return "", err
+	}
+	switch {
+	case original.Spec.ProjectRef != run.Spec.ProjectRef:
+		return fmt.Sprintf("DbtRun %q belongs to another project", original.Name), nil
+	case !isRunFinished(original.Status.Phase):
+		return fmt.Sprintf("DbtRun %q has not finished", original.Name), nil
+	case rerunOf.FromFailure && original.Status.StateVolumeClaim == "":
+		return fmt.Sprintf("DbtRun %q kept no dbt state to rerun from failure as dbt never ran", original.Name), nil
+	}
+
+	if rerunOf.FromFailure {
+		var pvc corev1.PersistentVolumeClaim
+		key := client.ObjectKey{Namespace: run.Namespace, Name: original.Status.StateVolumeClaim}
+		if err := r.Get(ctx, key, &pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Sprintf("state volume %q of DbtRun %q no longer exists", key.Name, original.Name), nil
+			}
+			return "", err
+		}
+		if !pvc.DeletionTimestamp.IsZero() {
+			return fmt.Sprintf("state volume %q of DbtRun %q is being deleted", key.Name, original.Name), nil
+		}
+		if err := controllerutil.SetOwnerReference(run, &pvc, r.Scheme); err != nil {
+			return "", err
+		}
+		if err := r.Update(ctx, &pvc); err != nil {
+			return "", err
+		}
+	}
+
+	if len(run.Spec.Commands) == 0 && run.Spec.Invocation == nil && len(run.Spec.Steps) == 0 {
+		// The original's recorded commands are pinned; runs that predate
+		// recording them resolve them again.
+		args, invocation, steps, err := resolveCommands(&original, project)
+		if err != nil {
+			return "", err
+		}
+		run.Status.Effective = &orchestrationv1alpha1.EffectiveRunConfig{Args: args, Invocation: invocation, Steps: steps}
+	}
+	commit := original.Status.Commit
+	if commit == "" {
+		commit = original.Annotations[commitAnnotation]
+	}
+	if run.Annotations[commitAnnotation] == "" && run.Spec.GitRef == "" {
+		run.Status.Commit = commit
+	}
+
+	if !slices.Contains(original.Status.Reruns, run.Name) {
+		original.Status.Reruns = append(original.Status.Reruns, run.Name)
+		if err := r.Status().Update(ctx, &original); err != nil {
+			return "", err
+		}
+	}
+
+	run.Status.RerunOf = original.Name
+	if rerunOf.FromFailure {
+		run.Status.StateVolumeClaim = original.Status.StateVolumeClaim
+		run.Status.Steps = original.Status.Steps
+		if n := len(original.Status.Attempts); n > 0 {
+			run.Status.ResumedAttempt = original.Status.Attempts[n-1].DeepCopy()
+		}
+	}
+	return "", nil
+}
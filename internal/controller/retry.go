@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	defaultRetryBackoff  = 30 * time.Second
+	defaultRetryMaxDelay = 10 * time.Minute
+
+	// maxRunNameLength bounds the names the operator gives runs, so that the
+	// Job of a run's last attempt, <run>-job-10, still fits into a label.
+	maxRunNameLength = validation.DNS1123LabelMaxLength - len("-job-10")
+
+	// stateMountPath is where the state volume of a retried run is mounted;
+	// dbt writes its target directory, and with it the run_results.json
+	// that dbt retry reads, below it.
+	stateMountPath  = "/dbt-state"
+	stateVolumeName = "dbt-state"
+)
+
+// retryPolicy returns the retry policy of a run, or nil.
+func retryPolicy(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) *orchestrationv1alpha1.RetryPolicy {
+	if run.Spec.RetryPolicy != nil {
+		return run.Spec.RetryPolicy
+	}
+	return project.Spec.RetryPolicy
+}
+
+// shouldRetry reports whether policy allows another attempt after the failed
+// attempt.
+func shouldRetry(policy *orchestrationv1alpha1.RetryPolicy, attempt *orchestrationv1alpha1.RunAttempt) bool {
+	if policy == nil || attempt.Attempt >= policy.MaxAttempts {
+		return false
+	}
+	return len(policy.On) == 0 || slices.Contains(policy.On, attempt.FailureClass)
+}
+
+// retryDelay is the time to wait after the failed attempt before the next
+// one. The backoff doubles with every retry until it reaches the policy's
+// maximum delay.
+func retryDelay(policy *orchestrationv1alpha1.RetryPolicy, attempt int32) time.Duration {
+	delay := defaultRetryBackoff
+	if policy.Backoff != nil {
+		delay = policy.Backoff.Duration
+	}
+	maxDelay := defaultRetryMaxDelay
+	if policy.MaxDelay != nil {
+		maxDelay = policy.MaxDelay.Duration
+	}
+	for i := int32(1); i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// jobName is the name of the Job of a run's attempt. The first attempt's Job
+// is named as before retries existed.
+func jobName(run *orchestrationv1alpha1.DbtRun, attempt int) string {
+	if attempt <= 1 {
+		return boundedName(fmt.Sprintf("%s-job", run.Name), validation.DNS1123LabelMaxLength)
+	}
+	return boundedName(fmt.Sprintf("%s-job-%d", run.Name, attempt), validation.DNS1123LabelMaxLength)
+}
+
+// boundedName returns name if it has at most limit characters. Longer names
+// are truncated and end in a hash of the full name instead, so that they stay
+// deterministic and distinct.
+func boundedName(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return strings.TrimRight(name[:limit-len(hash)-1], "-.") + "-" + hash
+}
+
+// currentAttempt returns the attempt of the run's current Job. Runs started
+// before attempts were recorded get one for their Job.
+func currentAttempt(run *orchestrationv1alpha1.DbtRun) *orchestrationv1alpha1.RunAttempt {
+	if len(run.Status.Attempts) == 0 {
+		run.Status.Attempts = []orchestrationv1alpha1.RunAttempt{{
+			Attempt:   1,
+			Job:       run.Status.JobRef.Name,
+			Phase:     orchestrationv1alpha1.RunPhaseRunning,
+			StartTime: run.Status.StartTime,
+		}}
+	}
+	return &run.Status.Attempts[len(run.Status.Attempts)-1]
+}
+
+// resumedAttempt returns the failed attempt that the current attempt picks
+// up from, or nil when it runs the run from the start. An attempt resumes
+// its predecessor, or the first attempt of a rerun from failure the
+// original's last attempt, when that one failed inside dbt.
+func resumedAttempt(run *orchestrationv1alpha1.DbtRun) *orchestrationv1alpha1.RunAttempt {
+	var previous *orchestrationv1alpha1.RunAttempt
+	if n := len(run.Status.Attempts); n >= 2 {
+		previous = &run.Status.Attempts[n-2]
+	} else {
+		previous = run.Status.ResumedAttempt
+	}
+	if previous == nil || !isDbtContainer(previous.FailedContainer) {
+		return nil
+	}
+	return previous
+}
+
+// retryArgs returns the dbt arguments of an attempt resuming previous: dbt
+// retry when dbt reported failed nodes, so that only those and the nodes
+// they skipped run again, or args again for failures that may have left no
+// run_results.json behind.
+func retryArgs(args []string, previous *orchestrationv1alpha1.RunAttempt) []string {
+	if previous.FailureClass == orchestrationv1alpha1.FailureClassNode {
+		return []string{"retry"}
+	}
+	return args
+}
+
+// retrySteps returns the steps of an attempt resuming previous. Steps before
+// the failed one already succeeded and are dropped, except those installing
+// packages into the fresh workspace; the failed step is retried and the
+// steps after it run as before.
+func retrySteps(steps []orchestrationv1alpha1.DbtStep, previous *orchestrationv1alpha1.RunAttempt) ([]orchestrationv1alpha1.DbtStep, error) {
+	failed := slices.IndexFunc(steps, func(step orchestrationv1alpha1.DbtStep) bool {
+		return stepContainerName(step) == previous.FailedContainer
+	})
+	if failed < 0 {
+		return steps, nil
+	}
+
+	var retried []orchestrationv1alpha1.DbtStep
+	for _, step := range steps[:failed] {
+		args, err := stepArgs(step)
+		if err != nil {
+			return nil, err
+		}
+		if args[0] == "deps" {
+			retried = append(retried, step)
+		}
+	}
+
+	step := steps[failed]
+	args, err := stepArgs(step)
+	if err != nil {
+		return nil, err
+	}
+	retried = append(retried, orchestrationv1alpha1.DbtStep{Name: step.Name, Commands: retryArgs(args, previous)})
+	return append(retried, steps[failed+1:]...), nil
+}
+
+// attemptSteps returns the steps the run's current attempt runs.
+func attemptSteps(run *orchestrationv1alpha1.DbtRun, steps []orchestrationv1alpha1.DbtStep) ([]orchestrationv1alpha1.DbtStep, error) {
+	if previous := resumedAttempt(run); previous != nil {
+		return retrySteps(steps, previous)
+	}
+	return steps, nil
+}
+
+// mergeStepStatuses returns the statuses of steps, taking those of the
+// current attempt where it runs the step and keeping the earlier ones of
+// steps a retry dropped.
+func mergeStepStatuses(steps []orchestrationv1alpha1.DbtStep, previous, current []orchestrationv1alpha1.StepStatus) []orchestrationv1alpha1.StepStatus {
+	merged := make([]orchestrationv1alpha1.StepStatus, 0, len(steps))
+	for _, step := range steps {
+		byName := func(status orchestrationv1alpha1.StepStatus) bool { return status.Name == step.Name }
+		if i := slices.IndexFunc(current, byName); i >= 0 {
+			merged = append(merged, current[i])
+		} else if i := slices.IndexFunc(previous, byName); i >= 0 {
+			merged = append(merged, previous[i])
+		} else {
+			merged = append(merged, orchestrationv1alpha1.StepStatus{Name: step.Name, Phase: orchestrationv1alpha1.StepPhasePending})
+		}
+	}
+	return merged
+}
+
+func isDbtContainer(name string) bool {
+	return name == "dbt" || strings.HasPrefix(name, "dbt-")
+}
+
+// classifyFailure finds the container that made pod fail and classifies the
+// failure by dbt's exit code. pod may be nil when it is gone.
+func classifyFailure(pod *corev1.Pod) (orchestrationv1alpha1.FailureClass, string, *int32) {
+	if pod == nil {
+		return orchestrationv1alpha1.FailureClassPod, "", nil
+	}
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		exitCode := terminated.ExitCode
+		class := orchestrationv1alpha1.FailureClassPod
+		if isDbtContainer(status.Name) && terminated.Reason != "OOMKilled" {
+			switch exitCode {
+			case 1:
+				class = orchestrationv1alpha1.FailureClassNode
+			case 2:
+				class = orchestrationv1alpha1.FailureClassRuntime
+			}
+		}
+		return class, status.Name, &exitCode
+	}
+	return orchestrationv1alpha1.FailureClassPod, "", nil
+}
+
+// startAttempt creates the Job of the run's next attempt and makes it the
+// run's current Job.
+func (r *DbtRunReconciler) startAttempt(ctx context.Context, run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) error {
+	now := metav1.Now()
+	attempt := len(run.Status.Attempts) + 1
+	run.Status.Attempts = append(run.Status.Attempts, orchestrationv1alpha1.RunAttempt{
+		Attempt:   int32(attempt),
+		Job:       jobName(run, attempt),
+		Phase:     orchestrationv1alpha1.RunPhaseRunning,
+		StartTime: &now,
+	})
+
+	job, err := r.createJob(ctx, run, project)
+	if apierrors.IsAlreadyExists(err) {
+		// A previous reconcile created the Job but failed to record it.
+		job = &batchv1.Job{}
+		err = r.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: jobName(run, attempt)}, job)
+	}
+	if err != nil {
+		run.Status.Attempts = run.Status.Attempts[:attempt-1]
+		return err
+	}
+
+	run.Status.JobRef = &corev1.ObjectReference{
+		Kind:       "Job",
+		APIVersion: "batch/v1",
+		Name:       job.Name,
+		Namespace:  job.Namespace,
+		UID:        job.UID,
+	}
+	config := effectiveConfig(run, project)
+	if config.Args, config.Invocation, config.Steps, err = resolveCommands(run, project); err != nil {
+		return err
+	}
+	run.Status.Effective = &config
+	run.Status.JobStatus = nil
+	run.Status.NextRetryTime = nil
+	return nil
+}
+
+// ensureStateVolume creates the PVC keeping dbt's target directory between
+// the attempts of a run and for reruns from its failure. Every run keeps it;
+// the run's retry policy, if any, may give its spec.
+func (r *DbtRunReconciler) ensureStateVolume(ctx context.Context, run *orchestrationv1alpha1.DbtRun, policy *orchestrationv1alpha1.RetryPolicy) error {
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+		},
+	}
+	if policy != nil && policy.StateVolume != nil {
+		spec = *policy.StateVolume.DeepCopy()
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stateVolumeClaimName(run),
+			Namespace: run.Namespace,
+			Labels:    map[string]string{"orchestration.scalecraft.io/run": run.Name},
+		},
+		Spec: spec,
+	}
+	if err := controllerutil.SetControllerReference(run, pvc, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, pvc); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to create PersistentVolumeClaim %s: %w", pvc.Name, err)
+	}
+	log.FromContext(ctx).Info("Created PersistentVolumeClaim", "pvc", pvc.Name)
+	return nil
+}
+
+func stateVolumeClaimName(run *orchestrationv1alpha1.DbtRun) string {
+	return fmt.Sprintf("%s-%s", run.Name, stateVolumeName)
+}
@@ -0,0 +1,413 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// scheduleParser accepts standard five-field expressions, the six-field
+// form with a leading seconds field that DbtProject schedules have always
+// used, and @-descriptors such as @daily or @every 2h.
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// parseCron parses a cron expression in the given time zone, which defaults
+// to UTC. A CRON_TZ= or TZ= prefix on the expression is still honoured when
+// no time zone is given.
+func parseCron(expr, timeZone string) (cron.Schedule, error) {
+	spec := strings.TrimSpace(expr)
+
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if timeZone != "" {
+			return nil, fmt.Errorf("schedule %q must not set a time zone when timeZone is set", spec)
+		}
+		prefix, rest, _ := strings.Cut(spec, " ")
+		_, timeZone, _ = strings.Cut(prefix, "=")
+		spec = strings.TrimSpace(rest)
+	}
+
+	loc := time.UTC
+	if timeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", timeZone, err)
+		}
+	}
+
+	sched, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unparseable schedule %q: %w", expr, err)
+	}
+
+	cronSpec, ok := sched.(*cron.SpecSchedule)
+	if !ok {
+		// @every schedules are plain intervals and ignore the wall clock.
+		return sched, nil
+	}
+
+	// Schedules that fire every hour follow elapsed time across DST changes
+	// so that they neither stall nor bunch up; schedules pinned to specific
+	// hours follow the wall clock.
+	if cronSpec.Hour&allHours == allHours {
+		cronSpec.Location = loc
+		return cronSpec, nil
+	}
+	cronSpec.Location = time.UTC
+	return &wallClockSchedule{spec: cronSpec, loc: loc}, nil
+}
+
+// allHours is the hour bitmask of a schedule that fires in every hour.
+const allHours = 1<<24 - 1
+
+// wallClockSchedule evaluates a cron expression against the wall clock of a
+// time zone. A fire time that is skipped by a DST change runs when the clock
+// jumps forward, shifted by the length of the gap, and a fire time that
+// occurs twice when the clock falls back only runs at its first occurrence.
+type wallClockSchedule struct {
+	spec *cron.SpecSchedule
+	loc  *time.Location
+}
+
+func (s *wallClockSchedule) Next(t time.Time) time.Time {
+	local := t.In(s.loc)
+	wall := time.Date(local.Year(), local.Month(), local.Day(),
+		local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	for {
+		wall = s.spec.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		if next := resolveWallClock(wall, s.loc); next.After(t) {
+			return next
+		}
+	}
+}
+
+// resolveWallClock returns the earliest instant at which the clock in loc
+// shows the wall time (given in UTC), or, when that wall time does not exist
+// because the clock jumped forward, the instant it would have been had the
+// clock not changed.
+func resolveWallClock(wall time.Time, loc *time.Location) time.Time {
+	_, offsetBefore := wall.Add(-12 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(12 * time.Hour).In(loc).Zone()
+
+	before := wall.Add(-time.Duration(offsetBefore) * time.Second)
+	after := wall.Add(-time.Duration(offsetAfter) * time.Second)
+
+	var resolved time.Time
+	for _, candidate := range []time.Time{before, after} {
+		local := candidate.In(loc)
+		if local.Hour() != wall.Hour() || local.Minute() != wall.Minute() || local.Day() != wall.Day() {
+			continue
+		}
+		if resolved.IsZero() || candidate.Before(resolved) {
+			resolved = candidate
+		}
+	}
+	if resolved.IsZero() {
+		resolved = before
+	}
+
+	return resolved.In(loc)
+}
+
+const (
+	// scheduledTimeAnnotation records the logical fire time a scheduled
+	// DbtRun stands for, which differs from its creation time when catching up.
+	scheduledTimeAnnotation = "orchestration.scalecraft.io/scheduled-time"
+
+	// scheduleLabel names the schedule that created a DbtRun.
+	scheduleLabel = "orchestration.scalecraft.io/schedule"
+
+	// defaultScheduleName identifies the project's top-level schedule.
+	defaultScheduleName = "default"
+
+	// maxCatchUpRuns bounds how many missed ticks a single reconcile starts
+	// under CatchUpAll; the remainder is picked up by the next reconcile.
+	maxCatchUpRuns = 50
+
+	// defaultOnTimeWindow is how late a tick may be started under CatchUpNone
+	// when the project does not set startingDeadlineSeconds.
+	defaultOnTimeWindow = time.Minute
+
+	// upcomingScheduleCount is how many future fire times are published in
+	// the project status.
+	upcomingScheduleCount = 3
+
+	// maxUpcomingLookahead bounds how many blacked-out fire times are
+	// stepped over when looking for upcoming ones.
+	maxUpcomingLookahead = 1000
+)
+
+// getMissedSchedules looks at one of the project's schedules from its last
+// recorded fire time (or the project's creation when it has never fired) up
+// to now. It returns the due fire times that should still be started
+// according to the project's catch-up, concurrency policy and starting
+// deadline, oldest first, together with the first fire time after now.
+func getMissedSchedules(project *orchestrationv1alpha1.DbtProject, schedule string, lastScheduled *metav1.Time, now time.Time) ([]time.Time, time.Time, error) {
+	sched, err := parseCron(schedule, project.Spec.TimeZone)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	earliest := project.CreationTimestamp.Time
+	if lastScheduled != nil {
+		earliest = lastScheduled.Time
+	}
+
+	policy := project.Spec.CatchUp
+	if policy == "" {
+		policy = orchestrationv1alpha1.CatchUpLatestOnly
+	}
+	// Only one run can be active under Forbid and Replace, so catching up
+	// on more than the latest tick would just skip or cancel the others.
+	if concurrency := project.Spec.ConcurrencyPolicy; concurrency != "" && concurrency != orchestrationv1alpha1.AllowConcurrent {
+		if policy == orchestrationv1alpha1.CatchUpAll {
+			policy = orchestrationv1alpha1.CatchUpLatestOnly
+		}
+	}
+
+	// Ticks older than the starting deadline are never started, so there is
+	// no point in walking the schedule before it.
+	var deadline time.Time
+	if project.Spec.StartingDeadlineSeconds != nil {
+		deadline = now.Add(-time.Duration(*project.Spec.StartingDeadlineSeconds) * time.Second)
+	} else if policy == orchestrationv1alpha1.CatchUpNone {
+		deadline = now.Add(-defaultOnTimeWindow)
+	}
+	if deadline.After(earliest) {
+		earliest = deadline
+	}
+
+	next := sched.Next(now)
+	if earliest.After(now) {
+		return nil, next, nil
+	}
+
+	if policy != orchestrationv1alpha1.CatchUpAll {
+		if latest := latestFireTime(sched, earliest, now); !latest.IsZero() {
+			return []time.Time{latest}, next, nil
+		}
+		return nil, next, nil
+	}
+
+	var missed []time.Time
+	for t := sched.Next(earliest); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		missed = append(missed, t)
+		if len(missed) == maxCatchUpRuns {
+			break
+		}
+	}
+
+	return missed, next, nil
+}
+
+// latestFireTime returns the latest fire time of sched after earliest and
+// not after now, or the zero time when there is none. Rather than walking
+// every fire time since earliest, which for a frequent schedule that was
+// suspended for long would be millions, it searches windows before now that
+// double in size until one contains a fire time.
+func latestFireTime(sched cron.Schedule, earliest, now time.Time) time.Time {
+	if every, ok := sched.(cron.ConstantDelaySchedule); ok {
+		// Intervals count from earliest, so the latest is computed directly.
+		first := every.Next(earliest)
+		if first.After(now) {
+			return time.Time{}
+		}
+		return first.Add(now.Sub(first) / every.Delay * every.Delay)
+	}
+
+	for window := time.Minute; ; window *= 2 {
+		from := now.Add(-window)
+		if !from.After(earliest) {
+			from = earliest
+		}
+		var latest time.Time
+		for t := sched.Next(from); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+			latest = t
+		}
+		if !latest.IsZero() || from.Equal(earliest) {
+			return latest
+		}
+	}
+}
+
+// getUpcomingSchedules returns the next few fire times of one of the
+// project's schedules at which a run would actually start, i.e. that are not
+// inside a blackout.
+func getUpcomingSchedules(project *orchestrationv1alpha1.DbtProject, schedule string, now time.Time, blackouts blackoutRules) ([]time.Time, error) {
+	sched, err := parseCron(schedule, project.Spec.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var upcoming []time.Time
+	t := now
+	for range maxUpcomingLookahead {
+		if t = sched.Next(t); t.IsZero() {
+			break
+		}
+		if blackouts.blockedBy(t) != "" {
+			continue
+		}
+		if upcoming = append(upcoming, t); len(upcoming) == upcomingScheduleCount {
+			break
+		}
+	}
+
+	return upcoming, nil
+}
+
+// setUpcomingSchedules publishes the next fire times of all of the project's
+// schedules in its status and reports whether the status changed.
+func setUpcomingSchedules(status *orchestrationv1alpha1.DbtProjectStatus, upcoming []time.Time) bool {
+	upcoming = slices.Clone(upcoming)
+	slices.SortFunc(upcoming, time.Time.Compare)
+	upcoming = slices.CompactFunc(upcoming, time.Time.Equal)
+	if len(upcoming) > upcomingScheduleCount {
+		upcoming = upcoming[:upcomingScheduleCount]
+	}
+
+	var next *metav1.Time
+	var times []metav1.Time
+	for _, t := range upcoming {
+		times = append(times, metav1.NewTime(t))
+	}
+	if len(times) > 0 {
+		next = &times[0]
+	}
+
+	if equality.Semantic.DeepEqual(next, status.NextScheduleTime) &&
+		equality.Semantic.DeepEqual(times, status.UpcomingScheduleTimes) {
+		return false
+	}
+
+	status.NextScheduleTime = next.DeepCopy()
+	status.UpcomingScheduleTimes = times
+	return true
+}
+
+// formatTick renders a fire time for events and condition messages.
+func formatTick(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// projectSchedules returns the project's top-level schedule, under the name
+// "default", followed by its named schedules.
+func projectSchedules(project *orchestrationv1alpha1.DbtProject) ([]orchestrationv1alpha1.NamedSchedule, error) {
+	var schedules []orchestrationv1alpha1.NamedSchedule
+	if project.Spec.Schedule != "" {
+		schedules = append(schedules, orchestrationv1alpha1.NamedSchedule{
+			Name:     defaultScheduleName,
+			Schedule: project.Spec.Schedule,
+			Commands: project.Spec.Commands,
+		})
+	}
+
+	for _, schedule := range project.Spec.Schedules {
+		if project.Spec.Schedule != "" && schedule.Name == defaultScheduleName {
+			return nil, fmt.Errorf("schedule name %q is reserved for spec.schedule", defaultScheduleName)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+// validateSchedules checks the project's time zone, blackout windows and
+// schedules, returning the first error.
+func validateSchedules(project *orchestrationv1alpha1.DbtProject) error {
+	if _, err := newBlackoutWindowRules("project", project.Spec.BlackoutWindows, project.Spec.TimeZone); err != nil {
+		return &blackoutsError{reason: orchestrationv1alpha1.ProjectReasonInvalidBlackoutWindow, err: err}
+	}
+
+	if timeZone := project.Spec.TimeZone; timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q: %w", timeZone, err)
+		}
+	}
+
+	schedules, err := projectSchedules(project)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if _, err := parseCron(schedule.Schedule, project.Spec.TimeZone); err != nil {
+			return fmt.Errorf("schedule %q: %w", schedule.Name, err)
+		}
+	}
+	return nil
+}
+
+// findSchedule returns the project's schedule with the given name, or nil.
+func findSchedule(project *orchestrationv1alpha1.DbtProject, name string) *orchestrationv1alpha1.NamedSchedule {
+	for i := range project.Spec.Schedules {
+		if project.Spec.Schedules[i].Name == name {
+			return &project.Spec.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// scheduleStatusFor returns the status entry of the named schedule, adding
+// it when missing. A new entry for the default schedule inherits the
+// top-level lastScheduledTime, which is where its state used to be kept.
+func scheduleStatusFor(status *orchestrationv1alpha1.DbtProjectStatus, name string) *orchestrationv1alpha1.ScheduleStatus {
+	for i := range status.Schedules {
+		if status.Schedules[i].Name == name {
+			return &status.Schedules[i]
+		}
+	}
+
+	entry := orchestrationv1alpha1.ScheduleStatus{Name: name}
+	if name == defaultScheduleName {
+		entry.LastScheduledTime = status.LastScheduledTime.DeepCopy()
+	}
+	status.Schedules = append(status.Schedules, entry)
+	return &status.Schedules[len(status.Schedules)-1]
+}
+
+// scheduledRunName derives a deterministic DbtRun name from the schedule and
+// fire time so that concurrent or repeated reconciles of the same tick
+// converge on a single run instead of creating duplicates.
+func scheduledRunName(project *orchestrationv1alpha1.DbtProject, scheduleName string, scheduledTime time.Time) string {
+	if scheduleName == defaultScheduleName {
+		return boundedName(fmt.Sprintf("%s-%d", project.Name, scheduledTime.Unix()), maxRunNameLength)
+	}
+	return boundedName(fmt.Sprintf("%s-%s-%d", project.Name, scheduleName, scheduledTime.Unix()), maxRunNameLength)
+}
+
+// pruneScheduleStatuses drops the status entries of schedules that no longer
+// exist and reports whether any were dropped.
+func pruneScheduleStatuses(status *orchestrationv1alpha1.DbtProjectStatus, schedules []orchestrationv1alpha1.NamedSchedule) bool {
+	count := len(status.Schedules)
+	status.Schedules = slices.DeleteFunc(status.Schedules, func(entry orchestrationv1alpha1.ScheduleStatus) bool {
+		return !slices.ContainsFunc(schedules, func(schedule orchestrationv1alpha1.NamedSchedule) bool {
+			return schedule.Name == entry.Name
+		})
+	})
+	return len(status.Schedules) != count
+}
+
+// runScheduleName returns the name of the schedule a run belongs to, or ""
+// for runs not started by a schedule, such as manual runs and backfills.
+// Scheduled runs created before named schedules existed have no schedule
+// label and count towards the default schedule.
+func runScheduleName(run *orchestrationv1alpha1.DbtRun) string {
+	if name := run.Labels[scheduleLabel]; name != "" {
+		return name
+	}
+	if run.Spec.Type == orchestrationv1alpha1.RunTypeScheduled {
+		return defaultScheduleName
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2025 ScaleCraft.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+var _ = Describe("Schedule parsing", func() {
+	newYork, err := time.LoadLocation("America/New_York")
+	Expect(err).NotTo(HaveOccurred())
+
+	fireTimes := func(schedule, timeZone string, from time.Time, count int) []string {
+		sched, err := parseCron(schedule, timeZone)
+		Expect(err).NotTo(HaveOccurred())
+
+		var times []string
+		for t := from; len(times) < count; {
+			t = sched.Next(t)
+			times = append(times, t.Format(time.RFC3339))
+		}
+		return times
+	}
+
+	DescribeTable("should compute fire times",
+		func(schedule, timeZone string, from time.Time, expected ...string) {
+			Expect(fireTimes(schedule, timeZone, from, len(expected))).To(Equal(expected))
+		},
+		Entry("six-field expressions", "0 30 6 * * *", "",
+			time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			"2025-01-01T06:30:00Z", "2025-01-02T06:30:00Z"),
+		Entry("five-field expressions", "30 6 * * 1-5", "",
+			time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC),
+			"2025-01-03T06:30:00Z", "2025-01-06T06:30:00Z"),
+		Entry("macros in the project's time zone", "@daily", "Europe/Berlin",
+			time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			"2025-01-02T00:00:00+01:00", "2025-01-03T00:00:00+01:00"),
+		Entry("a wall time skipped by spring forward once, after the gap", "30 2 * * *", "America/New_York",
+			time.Date(2025, 3, 8, 12, 0, 0, 0, newYork),
+			"2025-03-09T03:30:00-04:00", "2025-03-10T02:30:00-04:00"),
+		Entry("a wall time repeated by fall back only once", "30 1 * * *", "America/New_York",
+			time.Date(2025, 11, 1, 12, 0, 0, 0, newYork),
+			"2025-11-02T01:30:00-04:00", "2025-11-03T01:30:00-05:00"),
+		Entry("hourly schedules by elapsed time across fall back", "0 * * * *", "America/New_York",
+			time.Date(2025, 11, 2, 0, 30, 0, 0, newYork),
+			"2025-11-02T01:00:00-04:00", "2025-11-02T01:00:00-05:00", "2025-11-02T02:00:00-05:00"),
+	)
+
+	It("should keep run and Job names of maximum-length names within label limits", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("p", validation.DNS1123LabelMaxLength)},
+		}
+		schedule := strings.Repeat("s", 40)
+		tick := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		names := []string{
+			scheduledRunName(project, schedule, tick),
+			scheduledRunName(project, schedule, tick.Add(time.Hour)),
+			scheduledRunName(project, defaultScheduleName, tick),
+			upstreamRunName(project, tick),
+			backfillRunName(&orchestrationv1alpha1.DbtBackfill{ObjectMeta: project.ObjectMeta},
+				orchestrationv1alpha1.BackfillInterval{Index: 999}, 10),
+		}
+		for _, name := range names {
+			Expect(len(name)).To(BeNumerically("<=", maxRunNameLength), name)
+			run := &orchestrationv1alpha1.DbtRun{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			Expect(validation.IsDNS1123Label(jobName(run, 10))).To(BeEmpty(), name)
+			Expect(validation.IsValidLabelValue(name)).To(BeEmpty(), name)
+		}
+		Expect(names[0]).To(Equal(scheduledRunName(project, schedule, tick)))
+		Expect(names[0]).NotTo(Equal(names[1]))
+
+		short := &orchestrationv1alpha1.DbtProject{ObjectMeta: metav1.ObjectMeta{Name: "analytics"}}
+		Expect(scheduledRunName(short, "hourly", tick)).To(Equal("analytics-hourly-1893456000"))
+	})
+
+	It("should reject blackout windows that cannot be evaluated", func() {
+		start := metav1.NewTime(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+		for _, window := range []orchestrationv1alpha1.BlackoutWindow{
+			{Name: "bad-cron", Schedule: "61 * * * *", Duration: &metav1.Duration{Duration: time.Hour}},
+			{Name: "no-duration", Schedule: "0 * * * *"},
+			{Name: "backwards", Start: &start, End: &metav1.Time{Time: start.Add(-time.Hour)}},
+		} {
+			project := &orchestrationv1alpha1.DbtProject{Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Schedule:        "@hourly",
+				BlackoutWindows: []orchestrationv1alpha1.BlackoutWindow{window},
+			}}
+			err := validateSchedules(project)
+			Expect(err).To(MatchError(ContainSubstring(window.Name)))
+			var invalid *blackoutsError
+			Expect(errors.As(err, &invalid)).To(BeTrue())
+			Expect(invalid.reason).To(Equal(orchestrationv1alpha1.ProjectReasonInvalidBlackoutWindow))
+		}
+	})
+
+	It("should reject unknown time zones", func() {
+		_, err := parseCron("@hourly", "Mars/Olympus_Mons")
+		Expect(err).To(MatchError(ContainSubstring("unknown time zone")))
+	})
+})
+
+var _ = Describe("Missed schedules", func() {
+	lastScheduled := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := lastScheduled.AddDate(0, 1, 0).Add(1500 * time.Millisecond)
+
+	missed := func(spec orchestrationv1alpha1.DbtProjectSpec, schedule string) []time.Time {
+		project := &orchestrationv1alpha1.DbtProject{Spec: spec}
+		ticks, _, err := getMissedSchedules(project, schedule, &metav1.Time{Time: lastScheduled}, now)
+		Expect(err).NotTo(HaveOccurred())
+		return ticks
+	}
+
+	DescribeTable("should find the latest tick of a frequent schedule suspended for a month",
+		func(schedule string) {
+			Expect(missed(orchestrationv1alpha1.DbtProjectSpec{}, schedule)).To(Equal([]time.Time{now.Truncate(time.Second)}))
+		},
+		Entry("a cron expression", "* * * * * *"),
+		Entry("an interval", "@every 1s"),
+	)
+
+	It("should find no tick when none is due", func() {
+		Expect(missed(orchestrationv1alpha1.DbtProjectSpec{}, "0 0 0 1 1 *")).To(BeEmpty())
+	})
+
+	It("should catch up on the latest tick rather than the oldest under Forbid", func() {
+		ticks := missed(orchestrationv1alpha1.DbtProjectSpec{
+			CatchUp:           orchestrationv1alpha1.CatchUpAll,
+			ConcurrencyPolicy: orchestrationv1alpha1.ForbidConcurrent,
+		}, "@hourly")
+		Expect(ticks).To(Equal([]time.Time{now.Truncate(time.Hour)}))
+	})
+
+	It("should catch up on the oldest ticks first when runs may overlap", func() {
+		ticks := missed(orchestrationv1alpha1.DbtProjectSpec{CatchUp: orchestrationv1alpha1.CatchUpAll}, "@hourly")
+		Expect(ticks).To(HaveLen(maxCatchUpRuns))
+		Expect(ticks[0]).To(Equal(lastScheduled.Add(time.Hour)))
+	})
+})
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// fetchSourceContainerName is the init container materializing sources
+	// other than git.
+	fetchSourceContainerName = "fetch-source"
+
+	workspaceDir = "/workspace"
+
+	// sourceVolumeName and sourceMountPath are the volume of a ConfigMap or
+	// VolumeClaim source and where it is mounted to be copied.
+	sourceVolumeName = "source"
+	sourceMountPath  = "/source"
+
+	// ociAuthMountPath is where an OCISource.PullSecret is mounted.
+	ociAuthMountPath = "/etc/oci-auth"
+
+	orasImage  = "ghcr.io/oras-project/oras:v1.2.0"
+	fetchImage = "alpine:3.20"
+)
+
+// fetchTarballScript downloads the tarball at $CHECKOUT_URL, verifies it
+// against $CHECKOUT_SHA256 and extracts it into $CHECKOUT_DIR. As in
+// gitCheckoutScript, its inputs are only ever used quoted.
+const fetchTarballScript = `set -eu
+archive=$(mktemp)
+wget -q -O "$archive" "$CHECKOUT_URL"
+echo "$CHECKOUT_SHA256  $archive" | sha256sum -c -
+tar -xzf "$archive" -C "$CHECKOUT_DIR" --strip-components="${CHECKOUT_STRIP_COMPONENTS:-0}"
+rm -f "$archive"
+echo "extracted $CHECKOUT_URL"
+`
+
+// copySourceScript copies the files of the volume mounted at $CHECKOUT_SOURCE
+// into $CHECKOUT_DIR, resolving the symlinks a ConfigMap volume consists of
+// and skipping its ..data bookkeeping.
+const copySourceScript = `set -eu
+for entry in "$CHECKOUT_SOURCE"/* "$CHECKOUT_SOURCE"/.[!.]*; do
+	if [ -e "$entry" ]; then
+		cp -RL "$entry" "$CHECKOUT_DIR"
+	fi
+done
+`
+
+// projectGit returns the git repository of the project's source, or nil
+// when the project does not come from git.
+func projectGit(project *orchestrationv1alpha1.DbtProject) *orchestrationv1alpha1.GitConfig {
+	if project.Spec.Source != nil {
+		return project.Spec.Source.Git
+	}
+	if project.Spec.Git.Repository == "" {
+		return nil
+	}
+	return &project.Spec.Git
+}
+
+// projectPath returns the directory of the dbt project within its source.
+func projectPath(project *orchestrationv1alpha1.DbtProject) string {
+	if source := project.Spec.Source; source != nil && source.Path != "" {
+		return source.Path
+	}
+	if git := projectGit(project); git != nil {
+		return git.Path
+	}
+	return ""
+}
+
+// sourceContainer returns the init container that materializes the project's
+// source into the workspace, checking out ref for git sources, and the
+// volumes it needs besides the workspace.
+func sourceContainer(project *orchestrationv1alpha1.DbtProject, ref string) (corev1.Container, []corev1.Volume, error) {
+	container := corev1.Container{
+		Name:  fetchSourceContainerName,
+		Image: fetchImage,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "workspace",
+				MountPath: workspaceDir,
+			},
+		},
+	}
+	var volumes []corev1.Volume
+
+	if git := projectGit(project); git != nil {
+		git = git.DeepCopy()
+		git.Path = projectPath(project)
+		container.Name = gitCloneContainerName
+		container.Image = "alpine/git:latest"
+		container.Command = []string{"sh", "-c", gitCheckoutScript}
+		container.Env = gitCheckoutEnv(git, ref, workspaceDir)
+
+		if git.SSHKeySecret != "" {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      "ssh-key",
+				MountPath: gitSSHMountPath,
+				ReadOnly:  true,
+			})
+			volumes = append(volumes, secretVolume("ssh-key", git.SSHKeySecret))
+		}
+		if git.AuthSecret != "" {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      "git-auth",
+				MountPath: gitAuthMountPath,
+				ReadOnly:  true,
+			})
+			volumes = append(volumes, secretVolume("git-auth", git.AuthSecret))
+		}
+		return container, volumes, nil
+	}
+
+	source := project.Spec.Source
+	switch {
+	case source == nil:
+		return container, nil, errors.New("project has no source")
+	case source.OCI != nil:
+		// The oras image has no shell; the repository cannot start with a
+		// dash, so the reference is never taken for a flag.
+		container.Image = orasImage
+		container.Command = []string{"oras", "pull", "--output", workspaceDir}
+		if source.OCI.PullSecret != "" {
+			container.Command = append(container.Command, "--registry-config", ociAuthMountPath+"/config.json")
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      "oci-auth",
+				MountPath: ociAuthMountPath,
+				ReadOnly:  true,
+			})
+			volume := secretVolume("oci-auth", source.OCI.PullSecret)
+			volume.Secret.Items = []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}}
+			volumes = append(volumes, volume)
+		}
+		container.Command = append(container.Command, source.OCI.Repository+"@"+source.OCI.Digest)
+
+	case source.HTTP != nil:
+		container.Command = []string{"sh", "-c", fetchTarballScript}
+		container.Env = []corev1.EnvVar{
+			{Name: "CHECKOUT_DIR", Value: workspaceDir},
+			{Name: "CHECKOUT_URL", Value: source.HTTP.URL},
+			{Name: "CHECKOUT_SHA256", Value: source.HTTP.SHA256},
+			{Name: "CHECKOUT_STRIP_COMPONENTS", Value: strconv.Itoa(int(source.HTTP.StripComponents))},
+		}
+
+	case source.ConfigMap != nil, source.VolumeClaim != nil:
+		container.Command = []string{"sh", "-c", copySourceScript}
+		container.Env = []corev1.EnvVar{
+			{Name: "CHECKOUT_DIR", Value: workspaceDir},
+			{Name: "CHECKOUT_SOURCE", Value: sourceMountPath},
+		}
+		mount := corev1.VolumeMount{
+			Name:      sourceVolumeName,
+			MountPath: sourceMountPath,
+			ReadOnly:  true,
+		}
+		volume := corev1.Volume{Name: sourceVolumeName}
+		if source.ConfigMap != nil {
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: source.ConfigMap.Name},
+				Items:                source.ConfigMap.Items,
+			}
+		} else {
+			volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: source.VolumeClaim.ClaimName,
+				ReadOnly:  true,
+			}
+			mount.SubPath = source.VolumeClaim.SubPath
+		}
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+		volumes = append(volumes, volume)
+	default:
+		return container, nil, errors.New("project source has no kind")
+	}
+	return container, volumes, nil
+}
+
+// secretVolume returns a read-only volume of the named Secret. Its files are
+// readable by any user so that pods running as non-root can use them; only
+// the init container that needs a Secret mounts it.
+func secretVolume(name, secretName string) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  secretName,
+				DefaultMode: ptr.To(int32(0444)),
+			},
+		},
+	}
+}
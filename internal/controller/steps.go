@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// runSteps returns the steps of a run, or nil when it runs a single dbt
+// command.
+func runSteps(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) []orchestrationv1alpha1.DbtStep {
+	if len(run.Spec.Steps) > 0 {
+		return run.Spec.Steps
+	}
+	if len(run.Spec.Commands) > 0 || run.Spec.Invocation != nil {
+		return nil
+	}
+	return project.Spec.Steps
+}
+
+// stepsWithDefaults returns a copy of steps whose invocations are merged over
+// defaults, the project's invocation, like the invocation of a single
+// command run is. Steps with raw commands are kept as they are.
+func stepsWithDefaults(steps []orchestrationv1alpha1.DbtStep, defaults *orchestrationv1alpha1.DbtInvocation) []orchestrationv1alpha1.DbtStep {
+	if defaults == nil {
+		return steps
+	}
+	merged := make([]orchestrationv1alpha1.DbtStep, 0, len(steps))
+	for _, step := range steps {
+		step = *step.DeepCopy()
+		if len(step.Commands) == 0 {
+			invocation := mergeInvocations(defaults, step.Invocation)
+			step.Invocation = &invocation
+		}
+		merged = append(merged, step)
+	}
+	return merged
+}
+
+func stepArgs(step orchestrationv1alpha1.DbtStep) ([]string, error) {
+	if len(step.Commands) > 0 {
+		return step.Commands, nil
+	}
+	return invocationArgs(mergeInvocations(nil, step.Invocation))
+}
+
+// stepsWithVars returns a copy of steps that passes vars to every step: they
+// are merged over the vars of a step's invocation, or appended to its
+// commands.
+func stepsWithVars(steps []orchestrationv1alpha1.DbtStep, vars map[string]apiextensionsv1.JSON) ([]orchestrationv1alpha1.DbtStep, error) {
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vars: %w", err)
+	}
+	withVars := make([]orchestrationv1alpha1.DbtStep, 0, len(steps))
+	for _, step := range steps {
+		step = *step.DeepCopy()
+		if len(step.Commands) > 0 {
+			if step.Commands, err = appendVars(step.Commands, string(encoded)); err != nil {
+				return nil, fmt.Errorf("step %s: %w", step.Name, err)
+			}
+		} else {
+			merged := mergeInvocations(step.Invocation, &orchestrationv1alpha1.DbtInvocation{Vars: vars})
+			step.Invocation = &merged
+		}
+		withVars = append(withVars, step)
+	}
+	return withVars, nil
+}
+
+// stepContainerName is the name of the container running step; the prefix
+// keeps step names from clashing with the operator's own containers.
+func stepContainerName(step orchestrationv1alpha1.DbtStep) string {
+	return "dbt-" + step.Name
+}
+
+// stepContainers returns a container per step, all but the last to be run as
+// init containers so that the steps run in order and a failing step stops
+// the pod. Each is a copy of base with the step's command, run by python.
+func stepContainers(steps []orchestrationv1alpha1.DbtStep, base corev1.Container, python string) ([]corev1.Container, []string, error) {
+	var containers []corev1.Container
+	var commands []string
+	for _, step := range steps {
+		args, err := stepArgs(step)
+		if err != nil {
+			return nil, nil, err
+		}
+		container := *base.DeepCopy()
+		container.Name = stepContainerName(step)
+		container.Command = dbtCommand(python, step.ContinueOnFailure)
+		container.Args = args
+		containers = append(containers, container)
+		commands = append(commands, "dbt "+strings.Join(args, " "))
+	}
+	return containers, commands, nil
+}
+
+// stepStatuses reports the progress of the steps from the containers of pod,
+// which may be nil. Steps that have not started once the run is finished
+// were skipped.
+func stepStatuses(steps []orchestrationv1alpha1.DbtStep, pod *corev1.Pod, finished bool) []orchestrationv1alpha1.StepStatus {
+	containers := map[string]corev1.ContainerStatus{}
+	if pod != nil {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			containers[status.Name] = status
+		}
+	}
+
+	statuses := make([]orchestrationv1alpha1.StepStatus, 0, len(steps))
+	for _, step := range steps {
+		status := orchestrationv1alpha1.StepStatus{Name: step.Name, Phase: orchestrationv1alpha1.StepPhasePending}
+		container := containers[stepContainerName(step)]
+		switch state := container.State; {
+		case state.Running != nil:
+			status.Phase = orchestrationv1alpha1.StepPhaseRunning
+			status.StartTime = timePtr(state.Running.StartedAt)
+		case state.Terminated != nil:
+			exitCode := state.Terminated.ExitCode
+			if step.ContinueOnFailure {
+				if code, err := strconv.Atoi(strings.TrimSpace(state.Terminated.Message)); err == nil {
+					exitCode = int32(code)
+				}
+			}
+			status.Phase = orchestrationv1alpha1.StepPhaseSucceeded
+			if exitCode != 0 {
+				status.Phase = orchestrationv1alpha1.StepPhaseFailed
+			}
+			status.ExitCode = &exitCode
+			status.StartTime = timePtr(state.Terminated.StartedAt)
+			status.CompletionTime = timePtr(state.Terminated.FinishedAt)
+		}
+		if finished && status.Phase == orchestrationv1alpha1.StepPhasePending {
+			status.Phase = orchestrationv1alpha1.StepPhaseSkipped
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func timePtr(t metav1.Time) *metav1.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
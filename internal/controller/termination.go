@@ -0,0 +1,75 @@
+package controller
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// defaultTerminationGracePeriodSeconds is how long dbt has to cancel its
+// queries once its pod is stopped, e.g. because the run timed out, unless the
+// project says otherwise.
+const defaultTerminationGracePeriodSeconds = 120
+
+// dbtEntrypoint runs dbt, as the container's first process, with the
+// arguments of the python command. SIGTERM, which the kubelet stops
+// containers with, raises KeyboardInterrupt as SIGINT does, on which dbt
+// cancels the queries it has in flight and exits. Without a handler SIGTERM
+// would not even reach dbt as the container's first process.
+const dbtEntrypoint = `import runpy, signal, sys
+signal.signal(signal.SIGTERM, signal.default_int_handler)
+sys.argv[0] = "dbt"
+`
+
+const runDbt = `runpy.run_module("dbt", run_name="__main__")
+`
+
+// continueOnFailureDbt runs dbt and always succeeds, leaving dbt's exit code
+// in the termination message so that the step's status still reports it.
+const continueOnFailureDbt = `try:
+    runpy.run_module("dbt", run_name="__main__")
+except SystemExit as exit:
+    code = exit.code or 0
+else:
+    code = 0
+with open("` + corev1.TerminationMessagePathDefault + `", "w") as message:
+    message.write(str(code if isinstance(code, int) else 1))
+`
+
+// dbtCommand is the command of a container running dbt with python and its
+// arguments. The python must be able to import dbt, as in the dbt images.
+func dbtCommand(python string, continueOnFailure bool) []string {
+	if continueOnFailure {
+		return []string{python, "-c", dbtEntrypoint + continueOnFailureDbt}
+	}
+	return []string{python, "-c", dbtEntrypoint + runDbt}
+}
+
+// terminationGracePeriod returns the termination grace period of the pods
+// of a project's runs.
+func terminationGracePeriod(project *orchestrationv1alpha1.DbtProject) *int64 {
+	if project.Spec.TerminationGracePeriodSeconds != nil {
+		return project.Spec.TerminationGracePeriodSeconds
+	}
+	return ptr.To(int64(defaultTerminationGracePeriodSeconds))
+}
+
+// runTimeout returns the active deadline of the Jobs of a run, or nil.
+func runTimeout(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) *int64 {
+	if run.Spec.TimeoutSeconds != nil {
+		return run.Spec.TimeoutSeconds
+	}
+	return project.Spec.TimeoutSeconds
+}
+
+// jobTimedOut reports whether job failed by exceeding its active deadline.
+func jobTimedOut(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.Reason == batchv1.JobReasonDeadlineExceeded
+		}
+	}
+	return false
+}
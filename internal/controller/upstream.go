@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// triggeredByAnnotation lists the upstream runs whose success triggered a
+// DbtRun.
+const triggeredByAnnotation = "orchestration.scalecraft.io/triggered-by"
+
+// upstreamSuccesses returns, per upstream project, its latest run that
+// succeeded after since.
+func upstreamSuccesses(runs []orchestrationv1alpha1.DbtRun, upstreams []string, since time.Time) map[string]*orchestrationv1alpha1.DbtRun {
+	latest := map[string]*orchestrationv1alpha1.DbtRun{}
+	for i := range runs {
+		run := &runs[i]
+		if run.Status.Phase != orchestrationv1alpha1.RunPhaseSucceeded || run.Status.CompletionTime == nil ||
+			!run.Status.CompletionTime.After(since) || !slices.Contains(upstreams, run.Spec.ProjectRef.Name) {
+			continue
+		}
+		if current, ok := latest[run.Spec.ProjectRef.Name]; !ok || run.Status.CompletionTime.After(current.Status.CompletionTime.Time) {
+			latest[run.Spec.ProjectRef.Name] = run
+		}
+	}
+	return latest
+}
+
+// pendingUpstreams returns the upstream projects that still have to succeed
+// before a run is triggered, which under UpstreamAny is all of them until
+// the first one succeeds.
+func pendingUpstreams(triggers *orchestrationv1alpha1.ProjectTriggers, successes map[string]*orchestrationv1alpha1.DbtRun) []string {
+	var pending []string
+	for _, upstream := range triggers.AfterProjects {
+		if successes[upstream] == nil {
+			pending = append(pending, upstream)
+		}
+	}
+
+	if triggers.AfterProjectsPolicy != orchestrationv1alpha1.UpstreamAll && len(pending) < len(triggers.AfterProjects) {
+		return nil
+	}
+	return pending
+}
+
+// upstreamCycle returns the chain of afterProjects through which the named
+// project ends up running after itself, starting and ending with it, or nil.
+// Such a project would trigger itself forever, directly or through others.
+func upstreamCycle(projects []orchestrationv1alpha1.DbtProject, name string) []string {
+	upstreams := map[string][]string{}
+	for _, project := range projects {
+		if project.Spec.Triggers != nil {
+			upstreams[project.Name] = project.Spec.Triggers.AfterProjects
+		}
+	}
+
+	visited := map[string]bool{}
+	var chain []string
+	var visit func(project string) bool
+	visit = func(project string) bool {
+		chain = append(chain, project)
+		for _, upstream := range upstreams[project] {
+			if upstream == name {
+				chain = append(chain, name)
+				return true
+			}
+			if !visited[upstream] {
+				visited[upstream] = true
+				if visit(upstream) {
+					return true
+				}
+			}
+		}
+		chain = chain[:len(chain)-1]
+		return false
+	}
+	if visit(name) {
+		return chain
+	}
+	return nil
+}
+
+// upstreamRunName derives a deterministic DbtRun name from the completion
+// time of the newest upstream run so that repeated reconciles trigger once.
+func upstreamRunName(project *orchestrationv1alpha1.DbtProject, completed time.Time) string {
+	return boundedName(fmt.Sprintf("%s-upstream-%d", project.Name, completed.Unix()), maxRunNameLength)
+}
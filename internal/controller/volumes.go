@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+// volumeClaimOwner returns the object the run's PVCs belong to: the project,
+// or the run itself for the Run scope.
+func volumeClaimOwner(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) client.Object {
+	if project.Spec.VolumeClaimScope == orchestrationv1alpha1.VolumeClaimScopeRun {
+		return run
+	}
+	return project
+}
+
+// volumeClaimName is the name of the PVC created from template for owner,
+// following the StatefulSet convention of <template>-<owner>.
+func volumeClaimName(template *corev1.PersistentVolumeClaim, owner client.Object) string {
+	return fmt.Sprintf("%s-%s", template.Name, owner.GetName())
+}
+
+// ensureVolumeClaims creates the PVCs of the project's VolumeClaimTemplates
+// that the run needs and does not exist yet. The owner reference lets the
+// garbage collector delete them with their project or run.
+func (r *DbtRunReconciler) ensureVolumeClaims(ctx context.Context, run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) error {
+	log := log.FromContext(ctx)
+
+	owner := volumeClaimOwner(run, project)
+	for i := range project.Spec.VolumeClaimTemplates {
+		template := &project.Spec.VolumeClaimTemplates[i]
+
+		labels := maps.Clone(template.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["orchestration.scalecraft.io/project"] = project.Name
+
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        volumeClaimName(template, owner),
+				Namespace:   project.Namespace,
+				Labels:      labels,
+				Annotations: maps.Clone(template.Annotations),
+			},
+			Spec: *template.Spec.DeepCopy(),
+		}
+		if err := controllerutil.SetControllerReference(owner, pvc, r.Scheme); err != nil {
+			return err
+		}
+
+		if err := r.Create(ctx, pvc); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return fmt.Errorf("failed to create PersistentVolumeClaim %s: %w", pvc.Name, err)
+		}
+		log.Info("Created PersistentVolumeClaim", "pvc", pvc.Name)
+	}
+	return nil
+}
+
+// volumeClaimVolumes returns the pod volumes for the run's PVCs, named after
+// their templates. The CRD keeps templates from taking the names of the
+// controller's own volumes.
+func volumeClaimVolumes(run *orchestrationv1alpha1.DbtRun, project *orchestrationv1alpha1.DbtProject) []corev1.Volume {
+	owner := volumeClaimOwner(run, project)
+	var volumes []corev1.Volume
+	for i := range project.Spec.VolumeClaimTemplates {
+		template := &project.Spec.VolumeClaimTemplates[i]
+		volumes = append(volumes, corev1.Volume{
+			Name: template.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: volumeClaimName(template, owner),
+				},
+			},
+		})
+	}
+	return volumes
+}
@@ -0,0 +1,406 @@
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+const (
+	// webhookTokenKey and webhookHMACKey are the keys of a project's webhook
+	// Secret that hold the bearer token and the HMAC key.
+	webhookTokenKey = "token"
+	webhookHMACKey  = "hmacKey"
+
+	// webhookSignatureHeader carries the hex HMAC-SHA256 of
+	// "<timestamp>.<body>" as "sha256=<hex>", where the timestamp is the Unix
+	// time in webhookTimestampHeader. Signed requests older or newer than
+	// maxWebhookClockSkew are rejected so that they cannot be replayed
+	// later; within it, the run a signed request creates is named after its
+	// signature so that a replay collides with it.
+	webhookSignatureHeader = "X-Signature-256"
+	webhookTimestampHeader = "X-Signature-Timestamp"
+	maxWebhookClockSkew    = 5 * time.Minute
+
+	// webhookIdempotencyKeyHeader optionally identifies the delivery of a
+	// request authorized by the bearer token. The run such a request creates
+	// is named after the key so that delivering it again collides with it.
+	webhookIdempotencyKeyHeader = "Idempotency-Key"
+
+	// webhookRunIDLength is the number of hex digits of the signature, or of
+	// the hash of the idempotency key, in the name of a request's run.
+	webhookRunIDLength = 16
+
+	// maxWebhookBodyBytes bounds the size of a webhook request body.
+	maxWebhookBodyBytes = 1 << 20
+)
+
+// WebhookHandler serves POST /projects/{namespace}/{name}/runs, which
+// starts a Webhook run of a project whose triggers.webhook is set. The
+// optional JSON body may override the project's commands and set dbt vars.
+// It responds with the name of the created run so that callers can poll it.
+type WebhookHandler struct {
+	client.Client
+	// Reader reads projects and Secrets uncached; it defaults to Client.
+	Reader client.Reader
+	Scheme *runtime.Scheme
+	Clock  clock.PassiveClock
+
+	muxOnce sync.Once
+	mux     *http.ServeMux
+}
+
+// webhookRequest is the optional body of a webhook trigger.
+type webhookRequest struct {
+	Commands []string       `json:"commands,omitempty"`
+	Vars     map[string]any `json:"vars,omitempty"`
+}
+
+// webhookResponse identifies the run a webhook trigger created.
+type webhookResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.muxOnce.Do(func() {
+		h.mux = http.NewServeMux()
+		h.mux.HandleFunc("POST /projects/{namespace}/{name}/runs", h.createRun)
+	})
+	h.mux.ServeHTTP(w, req)
+}
+
+func (h *WebhookHandler) createRun(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := log.FromContext(ctx).WithValues("namespace", req.PathValue("namespace"), "project", req.PathValue("name"))
+
+	// Credentials are checked before the body is read, so that requests
+	// without them cannot make the handler read bodies.
+	if !hasWebhookCredentials(req) {
+		log.Info("Rejected unauthenticated webhook request")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var project orchestrationv1alpha1.DbtProject
+	key := client.ObjectKey{Namespace: req.PathValue("namespace"), Name: req.PathValue("name")}
+	if err := h.reader().Get(ctx, key, &project); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.NotFound(w, req)
+			return
+		}
+		log.Error(err, "Failed to get project")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	// Projects without a webhook trigger are reported as missing so that
+	// callers cannot probe which projects exist.
+	if project.Spec.Triggers == nil || project.Spec.Triggers.Webhook == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	var secret corev1.Secret
+	key = client.ObjectKey{Namespace: project.Namespace, Name: project.Spec.Triggers.Webhook.SecretName}
+	if err := h.reader().Get(ctx, key, &secret); err != nil {
+		log.Error(err, "Failed to get webhook secret", "secret", key.Name)
+		http.Error(w, "webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	byToken, ok := authorizeWebhookHeaders(req, &secret, h.now())
+	if !ok {
+		log.Info("Rejected unauthenticated webhook request")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	// deliveryID identifies the delivery of the request, if it can be told
+	// apart from others, and names its run.
+	var deliveryID []byte
+	if byToken {
+		if key := req.Header.Get(webhookIdempotencyKeyHeader); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			deliveryID = sum[:]
+		}
+	} else {
+		signature, ok := webhookSignature(req, body, &secret)
+		if !ok {
+			log.Info("Rejected unauthenticated webhook request")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		deliveryID = signature
+	}
+
+	var request webhookRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	run, err := h.newRun(&project, request)
+	if errors.Is(err, errVarsConflict) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(err, "Failed to build webhook run")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if deliveryID != nil {
+		run.GenerateName = ""
+		run.Name = deliveryRunName(&project, deliveryID)
+	}
+	replaced, err := h.applyConcurrencyPolicy(ctx, &project, run)
+	if err != nil {
+		if errors.Is(err, errConcurrencyForbidden) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Error(err, "Failed to apply the concurrency policy")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.Create(ctx, run); err != nil {
+		if apierrors.IsAlreadyExists(err) && deliveryID != nil {
+			log.Info("Rejected replayed webhook request", "run", run.Name)
+			http.Error(w, "request was already delivered", http.StatusConflict)
+			return
+		}
+		log.Error(err, "Failed to create webhook run")
+		http.Error(w, "failed to create run", http.StatusInternalServerError)
+		return
+	}
+	log.Info("Created webhook run", "run", run.Name)
+	for i := range replaced {
+		if err := replaceRun(ctx, h.Client, &replaced[i]); err != nil {
+			// The new run still starts once the runs it replaces finish.
+			log.Error(err, "Failed to cancel replaced run", "run", replaced[i].Name)
+			continue
+		}
+		log.Info("Replacing active run", "run", replaced[i].Name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookResponse{Name: run.Name, Namespace: run.Namespace})
+}
+
+func (h *WebhookHandler) newRun(project *orchestrationv1alpha1.DbtProject, request webhookRequest) (*orchestrationv1alpha1.DbtRun, error) {
+	commands := request.Commands
+	var invocation *orchestrationv1alpha1.DbtInvocation
+	var steps []orchestrationv1alpha1.DbtStep
+	if len(commands) == 0 && len(project.Spec.Steps) > 0 {
+		// Without vars the run takes over the project's steps when it starts.
+		if len(request.Vars) > 0 {
+			vars, err := invocationVars(request.Vars)
+			if err != nil {
+				return nil, err
+			}
+			if steps, err = stepsWithVars(project.Spec.Steps, vars); err != nil {
+				return nil, err
+			}
+		}
+	} else if len(commands) == 0 && project.Spec.Invocation != nil {
+		// The vars are merged over the project's invocation when the run starts.
+		if len(request.Vars) > 0 {
+			vars, err := invocationVars(request.Vars)
+			if err != nil {
+				return nil, err
+			}
+			invocation = &orchestrationv1alpha1.DbtInvocation{Vars: vars}
+		}
+	} else {
+		if len(commands) == 0 {
+			commands = project.Spec.Commands
+		}
+		if len(request.Vars) > 0 {
+			if len(commands) == 0 {
+				commands = []string{"run"}
+			}
+			vars, err := json.Marshal(request.Vars)
+			if err != nil {
+				return nil, err
+			}
+			if commands, err = appendVars(commands, string(vars)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	run := &orchestrationv1alpha1.DbtRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: webhookRunNamePrefix(project),
+			Namespace:    project.Namespace,
+		},
+		Spec: orchestrationv1alpha1.DbtRunSpec{
+			ProjectRef: corev1.LocalObjectReference{
+				Name: project.Name,
+			},
+			Type:       orchestrationv1alpha1.RunTypeWebhook,
+			Commands:   commands,
+			Invocation: invocation,
+			Steps:      steps,
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(project, run, h.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+	return run, nil
+}
+
+// errConcurrencyForbidden is returned for a webhook run of a project whose
+// concurrency policy is Forbid while runs it started are active.
+var errConcurrencyForbidden = errors.New("a run the project started is still active and concurrencyPolicy is Forbid")
+
+// applyConcurrencyPolicy applies the project's concurrency policy to a
+// webhook run about to be created, as admitRun does for schedules and
+// triggers. Under Replace it returns the active runs the new one replaces;
+// they are cancelled only once it has been created, so that a replayed
+// request cancels nothing. As the request cannot wait for them to stop, the
+// run is created right away and only starts once they have.
+func (h *WebhookHandler) applyConcurrencyPolicy(ctx context.Context, project *orchestrationv1alpha1.DbtProject, run *orchestrationv1alpha1.DbtRun) ([]orchestrationv1alpha1.DbtRun, error) {
+	activeRuns, err := listActiveRuns(ctx, h.reader(), project)
+	if err != nil {
+		return nil, err
+	}
+	concurrent := concurrentRuns(activeRuns)
+	if len(concurrent) == 0 {
+		return nil, nil
+	}
+
+	switch project.Spec.ConcurrencyPolicy {
+	case orchestrationv1alpha1.ForbidConcurrent:
+		return nil, errConcurrencyForbidden
+	case orchestrationv1alpha1.ReplaceConcurrent:
+		metav1.SetMetaDataAnnotation(&run.ObjectMeta, replacesAnnotation, strings.Join(runNames(concurrent), ","))
+		return concurrent, nil
+	}
+	return nil, nil
+}
+
+// webhookRunNamePrefix is the generateName of a project's webhook runs,
+// truncated so that the names the API server generates from it, with five
+// random characters appended, stay within maxRunNameLength.
+func webhookRunNamePrefix(project *orchestrationv1alpha1.DbtProject) string {
+	prefix := project.Name + "-webhook-"
+	return prefix[:min(len(prefix), maxRunNameLength-5)]
+}
+
+// deliveryRunName names the run of a webhook request after its delivery ID,
+// the signature of a signed request or the hash of an idempotency key, so
+// that delivering the request again fails with AlreadyExists instead of
+// starting another run.
+func deliveryRunName(project *orchestrationv1alpha1.DbtProject, deliveryID []byte) string {
+	id := hex.EncodeToString(deliveryID)[:webhookRunIDLength]
+	prefix := project.Name + "-webhook-"
+	return prefix[:min(len(prefix), maxRunNameLength-len(id))] + id
+}
+
+func (h *WebhookHandler) reader() client.Reader {
+	return apiReader(h.Reader, h.Client)
+}
+
+func (h *WebhookHandler) now() time.Time {
+	if h.Clock == nil {
+		return time.Now()
+	}
+	return h.Clock.Now()
+}
+
+// hasWebhookCredentials reports whether a request carries a bearer token or
+// a signature at all.
+func hasWebhookCredentials(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") || req.Header.Get(webhookSignatureHeader) != ""
+}
+
+// authorizeWebhookHeaders checks the credentials in a request's headers,
+// before its body is read. It accepts a request carrying the Secret's bearer
+// token, reporting byToken, or one signed with the Secret's HMAC key at a
+// recent timestamp, whose signature webhookSignature must still verify.
+func authorizeWebhookHeaders(req *http.Request, secret *corev1.Secret, now time.Time) (byToken bool, ok bool) {
+	if token := secret.Data[webhookTokenKey]; len(token) > 0 {
+		bearer, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(bearer), token) == 1 {
+			return true, true
+		}
+	}
+
+	if key := secret.Data[webhookHMACKey]; len(key) > 0 {
+		unix, err := strconv.ParseInt(req.Header.Get(webhookTimestampHeader), 10, 64)
+		if err != nil {
+			return false, false
+		}
+		if skew := now.Sub(time.Unix(unix, 0)); skew > maxWebhookClockSkew || skew < -maxWebhookClockSkew {
+			return false, false
+		}
+		return false, req.Header.Get(webhookSignatureHeader) != ""
+	}
+
+	return false, false
+}
+
+// webhookSignature verifies the HMAC-SHA256 signature of a signed request's
+// timestamp and body made with the Secret's HMAC key, and returns it.
+func webhookSignature(req *http.Request, body []byte, secret *corev1.Secret) ([]byte, bool) {
+	key := secret.Data[webhookHMACKey]
+	if len(key) == 0 {
+		return nil, false
+	}
+	payload := append([]byte(req.Header.Get(webhookTimestampHeader)+"."), body...)
+	return validSignature(req.Header.Get(webhookSignatureHeader), payload, key)
+}
+
+// validSignature checks a "sha256=<hex>" HMAC-SHA256 signature of body and
+// returns it decoded.
+func validSignature(signature string, body, key []byte) ([]byte, bool) {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return nil, false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return got, hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2025 ScaleCraft.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	orchestrationv1alpha1 "github.com/scalecraft/dagctl-dbt/api/v1alpha1"
+)
+
+var _ = Describe("Webhook trigger", func() {
+	const projectName = "webhook-project"
+
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var handler *WebhookHandler
+
+	BeforeEach(func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-auth", Namespace: "default"},
+			Data: map[string][]byte{
+				webhookTokenKey: []byte("s3cret-token"),
+				webhookHMACKey:  []byte("s3cret-key"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: projectName, Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git:      orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Commands: []string{"build"},
+				Triggers: &orchestrationv1alpha1.ProjectTriggers{
+					Webhook: &orchestrationv1alpha1.WebhookTrigger{SecretName: secret.Name},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &orchestrationv1alpha1.DbtRun{}, client.InNamespace("default"))).To(Succeed())
+		})
+
+		handler = &WebhookHandler{Client: k8sClient, Scheme: k8sClient.Scheme(), Clock: clocktesting.NewFakePassiveClock(now)}
+	})
+
+	post := func(project, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/projects/default/"+project+"/runs", strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	signed := func(body string, signedAt time.Time) http.Header {
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte("s3cret-key"))
+		mac.Write([]byte(timestamp + "." + body))
+		return http.Header{
+			webhookTimestampHeader: {timestamp},
+			webhookSignatureHeader: {"sha256=" + hex.EncodeToString(mac.Sum(nil))},
+		}
+	}
+
+	createdRun := func(rec *httptest.ResponseRecorder) *orchestrationv1alpha1.DbtRun {
+		Expect(rec.Code).To(Equal(http.StatusCreated), rec.Body.String())
+		var response webhookResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(Succeed())
+
+		run := &orchestrationv1alpha1.DbtRun{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: response.Namespace, Name: response.Name}, run)).To(Succeed())
+		Expect(run.Spec.Type).To(Equal(orchestrationv1alpha1.RunTypeWebhook))
+		return run
+	}
+
+	It("should create a run for a valid bearer token", func() {
+		rec := post(projectName, "", http.Header{"Authorization": {"Bearer s3cret-token"}})
+		run := createdRun(rec)
+		Expect(run.Spec.Commands).To(Equal([]string{"build"}))
+	})
+
+	It("should apply command and vars overrides from a signed body", func() {
+		body := `{"commands": ["run", "-s", "tag:fivetran"], "vars": {"source": "stripe"}}`
+		rec := post(projectName, body, signed(body, now.Add(-time.Minute)))
+		run := createdRun(rec)
+		Expect(run.Spec.Commands).To(Equal([]string{"run", "-s", "tag:fivetran", "--vars", `{"source":"stripe"}`}))
+	})
+
+	It("should reject signed requests delivered again", func() {
+		header := signed("{}", now)
+		run := createdRun(post(projectName, "{}", header))
+		Expect(run.Name).To(HavePrefix(projectName + "-webhook-"))
+
+		By("refusing the replay without touching the run")
+		project := &orchestrationv1alpha1.DbtProject{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: projectName}, project)).To(Succeed())
+		project.Spec.ConcurrencyPolicy = orchestrationv1alpha1.ReplaceConcurrent
+		Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+		rec := post(projectName, "{}", header)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring("already delivered"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(run), run)).To(Succeed())
+		Expect(run.Spec.Cancel).To(BeFalse())
+
+		By("accepting the same body signed anew")
+		header = signed("{}", now.Add(time.Second))
+		second := createdRun(post(projectName, "{}", header))
+		Expect(second.Annotations).To(HaveKeyWithValue(replacesAnnotation, run.Name))
+	})
+
+	It("should reject bearer requests delivered again with the same idempotency key", func() {
+		header := http.Header{"Authorization": {"Bearer s3cret-token"}, webhookIdempotencyKeyHeader: {"load-2025-01-01"}}
+		run := createdRun(post(projectName, "", header))
+		Expect(run.Name).To(HavePrefix(projectName + "-webhook-"))
+
+		rec := post(projectName, "", header)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring("already delivered"))
+
+		By("accepting another idempotency key")
+		header.Set(webhookIdempotencyKeyHeader, "load-2025-01-02")
+		second := createdRun(post(projectName, "", header))
+		Expect(second.Name).NotTo(Equal(run.Name))
+	})
+
+	It("should check credentials before reading the body", func() {
+		body := strings.Repeat(" ", maxWebhookBodyBytes+1)
+		Expect(post(projectName, body, nil).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(projectName, body, http.Header{"Authorization": {"Bearer wrong"}}).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(projectName, body, http.Header{"Authorization": {"Bearer s3cret-token"}}).Code).To(Equal(http.StatusRequestEntityTooLarge))
+
+		By("refusing bodies that cannot be read as bad requests")
+		req := httptest.NewRequest(http.MethodPost, "/projects/default/"+projectName+"/runs", iotest.ErrReader(errors.New("connection reset")))
+		req.Header.Set("Authorization", "Bearer s3cret-token")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should pass vars to every step of a project with steps", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-steps-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+				Steps: []orchestrationv1alpha1.DbtStep{
+					{Name: "deps", Commands: []string{"deps"}},
+					{Name: "build", Invocation: &orchestrationv1alpha1.DbtInvocation{Command: "build", Select: []string{"tag:daily"}}},
+				},
+				Triggers: &orchestrationv1alpha1.ProjectTriggers{
+					Webhook: &orchestrationv1alpha1.WebhookTrigger{SecretName: "webhook-auth"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+
+		rec := post(project.Name, `{"vars": {"source": "stripe"}}`, http.Header{"Authorization": {"Bearer s3cret-token"}})
+		run := createdRun(rec)
+		Expect(run.Spec.Commands).To(BeEmpty())
+		Expect(run.Spec.Steps).To(HaveLen(2))
+		Expect(run.Spec.Steps[0].Commands).To(Equal([]string{"deps", "--vars", `{"source":"stripe"}`}))
+		args, err := stepArgs(run.Spec.Steps[1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(Equal([]string{"build", "--select", "tag:daily", "--vars", `{"source":"stripe"}`}))
+	})
+
+	It("should reject requests without valid credentials", func() {
+		Expect(post(projectName, "", nil).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(projectName, "", http.Header{"Authorization": {"Bearer wrong"}}).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(projectName, "{}", http.Header{webhookSignatureHeader: {"sha256=00"}}).Code).To(Equal(http.StatusUnauthorized))
+
+		By("rejecting replayed signed requests")
+		Expect(post(projectName, "{}", signed("{}", now.Add(-10*time.Minute))).Code).To(Equal(http.StatusUnauthorized))
+		header := signed("{}", now)
+		header.Del(webhookTimestampHeader)
+		Expect(post(projectName, "{}", header).Code).To(Equal(http.StatusUnauthorized))
+		header = signed("{}", now)
+		header.Set(webhookTimestampHeader, strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+		Expect(post(projectName, "{}", header).Code).To(Equal(http.StatusUnauthorized))
+
+		var runs orchestrationv1alpha1.DbtRunList
+		Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+		Expect(runs.Items).To(BeEmpty())
+	})
+
+	It("should reject vars for commands that already pass --vars", func() {
+		body := `{"commands": ["run", "--vars", "{\"source\": \"hubspot\"}"], "vars": {"source": "stripe"}}`
+		rec := post(projectName, body, http.Header{"Authorization": {"Bearer s3cret-token"}})
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("--vars"))
+
+		var runs orchestrationv1alpha1.DbtRunList
+		Expect(k8sClient.List(ctx, &runs, client.InNamespace("default"))).To(Succeed())
+		Expect(runs.Items).To(BeEmpty())
+	})
+
+	It("should apply the project's concurrency policy", func() {
+		project := &orchestrationv1alpha1.DbtProject{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: projectName}, project)).To(Succeed())
+		project.Spec.ConcurrencyPolicy = orchestrationv1alpha1.ForbidConcurrent
+		Expect(k8sClient.Update(ctx, project)).To(Succeed())
+
+		first := createdRun(post(projectName, "", http.Header{"Authorization": {"Bearer s3cret-token"}}))
+		first.Status.Phase = orchestrationv1alpha1.RunPhaseRunning
+		Expect(k8sClient.Status().Update(ctx, first)).To(Succeed())
+
+		By("refusing a second run under Forbid")
+		rec := post(projectName, "", http.Header{"Authorization": {"Bearer s3cret-token"}})
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring("concurrencyPolicy is Forbid"))
+
+		By("replacing the active run under Replace")
+		project.Spec.ConcurrencyPolicy = orchestrationv1alpha1.ReplaceConcurrent
+		Expect(k8sClient.Update(ctx, project)).To(Succeed())
+		second := createdRun(post(projectName, "", http.Header{"Authorization": {"Bearer s3cret-token"}}))
+		Expect(second.Annotations).To(HaveKeyWithValue(replacesAnnotation, first.Name))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(first), first)).To(Succeed())
+		Expect(first.Spec.Cancel).To(BeTrue())
+
+		By("holding the new run until the replaced one has stopped")
+		runReconciler := &DbtRunReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		result, err := runReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(second)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(replaceRequeueInterval))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(second), second)).To(Succeed())
+		Expect(second.Status.JobRef).To(BeNil())
+
+		_, err = runReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(first)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(first), first)).To(Succeed())
+		Expect(first.Status.Phase).To(Equal(orchestrationv1alpha1.RunPhaseCancelled))
+		stopping, err := replacedRunsStopping(ctx, k8sClient, second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stopping).To(BeFalse())
+	})
+
+	It("should not reveal projects without a webhook trigger", func() {
+		project := &orchestrationv1alpha1.DbtProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-webhook-project", Namespace: "default"},
+			Spec: orchestrationv1alpha1.DbtProjectSpec{
+				Git: orchestrationv1alpha1.GitConfig{Repository: "https://example.com/analytics.git"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, project)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, project)).To(Succeed())
+		})
+
+		missing := post("no-such-project", "", http.Header{"Authorization": {"Bearer s3cret-token"}})
+		Expect(missing.Code).To(Equal(http.StatusNotFound))
+		withoutWebhook := post(project.Name, "", http.Header{"Authorization": {"Bearer s3cret-token"}})
+		Expect(withoutWebhook.Code).To(Equal(http.StatusNotFound))
+		Expect(withoutWebhook.Body.String()).To(Equal(missing.Body.String()))
+	})
+})